@@ -2,9 +2,13 @@ package app
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/bunyk/gokeybr/fs"
+	"github.com/bunyk/gokeybr/layout"
+	"github.com/bunyk/gokeybr/race"
+	"github.com/bunyk/gokeybr/stats"
 	"github.com/bunyk/gokeybr/view"
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/encoding"
@@ -22,8 +26,10 @@ type App struct {
 	Timeline      []float64
 	InputPosition int
 	ErrorInput    []rune
+	Mistakes      []stats.Mistake
 	StartedAt     time.Time
 	Offset        int
+	rawStartedAt  time.Time // StartedAt by clock without pauses and cut idle time
 
 	Zen  bool
 	Mute bool
@@ -34,22 +40,68 @@ type App struct {
 	// For how long you could have your speed under speed limit and still continue typing
 	RemainingLife time.Duration
 
-	scr tcell.Screen
+	// When not zero, session ends after this time since first keystroke
+	Duration time.Duration
+	// When set, is called to get more text to type when text is about to end
+	More func() string
+
+	// Time between key presses longer than IdleLimit is cut to it, and saved to Gaps.
+	// Zero turns this off.
+	IdleLimit time.Duration
+	Gaps      []stats.Gap
+	lastKeyAt time.Time
+
+	// When true, every key event is saved to Events
+	RecordEvents bool
+	Events       []stats.KeyEvent
+
+	// Ghost is driven by timeline of previous session, or by constant speed
+	GhostTimeline []float64
+	GhostWPM      float64
+
+	// Connection to other racers, and time when race starts
+	Race             *race.Client
+	RaceStart        time.Time
+	reportedPosition int
+
+	// Pass of drill, NoPass for regular sessions
+	Pass Pass
+	// Speed required to succeed in fast and normal pass
+	TargetWPM float64
+	finished  bool // session is over, showing results
+
+	// Keyboard drawn under text, nil to not draw it, and how bad each key is typed
+	Keyboard *layout.Layout
+	KeyHeat  map[layout.Position]float64
+	// Runes typed are replaced by this table, to practice layout different from one set in system
+	Remap map[rune]rune
+
+	scr    tcell.Screen
+	clock  *PausableClock
+	events chan tcell.Event
 }
 
 func New(text string) (*App, error) {
+	encoding.Register()
+	scr, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithScreen(text, scr, RealClock{})
+}
+
+// NewWithScreen creates app that displays session on given screen,
+// and measures time with given clock. This allows to run it without terminal,
+// for example on tcell.SimulationScreen in tests.
+func NewWithScreen(text string, scr tcell.Screen, clock Clock) (*App, error) {
 	a := &App{}
 	a.ErrorInput = make([]rune, 0, 20)
 	a.Text = []rune(text)
 	a.Timeline = make([]float64, len(a.Text))
 	a.RemainingLife = InitialLife
-
-	encoding.Register()
-	var err error
-	if a.scr, err = tcell.NewScreen(); err != nil {
-		return a, err
-	}
-	if err = a.scr.Init(); err != nil {
+	a.scr = scr
+	a.clock = NewPausableClock(clock)
+	if err := a.scr.Init(); err != nil {
 		return a, err
 	}
 	return a, nil
@@ -65,41 +117,158 @@ func (t tick) When() time.Time {
 
 func (a *App) Run() error {
 	defer a.scr.Fini()
-	events := make(chan tcell.Event)
+	a.startEvents()
+	a.loop()
+	return nil
+}
+
+// startEvents starts goroutines that send keyboard events and timer ticks
+// to events channel
+func (a *App) startEvents() {
+	a.events = make(chan tcell.Event)
 	go func() {
 		for {
 			ev := a.scr.PollEvent()
-			events <- ev
+			a.events <- ev
 		}
 	}()
-	if !a.Zen {
+	// timed session needs ticks to end in time, and race to show progress of others
+	if !a.Zen || a.Duration > 0 || a.Race != nil {
 		go func() {
 			t := time.NewTicker(100 * time.Millisecond)
 			for {
 				<-t.C
-				events <- tick{}
+				a.events <- tick{}
 			}
 		}()
 	}
+}
 
+// loop processes events until session ends.
+// Returns false when user asked to quit.
+func (a *App) loop() bool {
+	a.extend()
 	for {
 		view.Render(a.scr, a.ToDisplay())
-		if a.RemainingLife <= 0 {
-			return nil
+		if a.over() {
+			return true
 		}
-		ev := <-events
-		switch event := ev.(type) {
-		case *tcell.EventKey:
-			if !a.processKey(event) {
-				if cheating {
-					a.InputPosition = 0
-				}
-				return nil
+		if !a.handle(<-a.events) {
+			return a.InputPosition == len(a.Text)
+		}
+	}
+}
+
+// over returns true when session ended because of time or speed limit
+func (a *App) over() bool {
+	return a.RemainingLife <= 0 || a.TimeIsUp()
+}
+
+// handle processes one event. Returns false when session should end.
+func (a *App) handle(ev tcell.Event) bool {
+	switch event := ev.(type) {
+	case *tcell.EventKey:
+		a.trimIdle()
+		wasPaused := a.clock.Paused()
+		cont := a.processKey(event)
+		if !wasPaused && !a.clock.Paused() { // keys that pause and resume are not typing
+			a.recordEvent(event)
+		}
+		a.reportProgress()
+		if !cont {
+			if cheating {
+				a.InputPosition = 0
 			}
-		case *tcell.EventResize:
-			a.scr.Sync()
+			return false
 		}
+	case *tcell.EventResize:
+		a.scr.Sync()
+	}
+	return true
+}
+
+// trimIdle cuts time since last key press to IdleLimit, so that breaks
+// in typing do not inflate timeline. Cut time is recorded in Gaps.
+func (a *App) trimIdle() {
+	defer func() {
+		a.lastKeyAt = a.clock.Now()
+	}()
+	if a.IdleLimit <= 0 || a.Race != nil || a.StartedAt.IsZero() || a.lastKeyAt.IsZero() {
+		return // nothing to trim, and others will not wait in race
+	}
+	gap := a.clock.Now().Sub(a.lastKeyAt)
+	if gap <= a.IdleLimit {
+		return
+	}
+	a.clock.Skip(gap - a.IdleLimit)
+	now := a.clock.Now()
+	if a.LastLifeReductionTime.After(now) {
+		a.LastLifeReductionTime = now
+	}
+	a.Gaps = append(a.Gaps, stats.Gap{
+		Position: a.InputPosition,
+		Time:     now.Sub(a.StartedAt).Seconds(),
+		Duration: gap.Seconds(),
+	})
+}
+
+func (a *App) recordEvent(ev *tcell.EventKey) {
+	if !a.RecordEvents {
+		return
+	}
+	ke := stats.KeyEvent{
+		Key:       keyName(ev.Key()),
+		Modifiers: int(ev.Modifiers()),
+		Position:  a.InputPosition,
+	}
+	if ev.Key() == tcell.KeyRune {
+		ke.Rune = string(ev.Rune())
+		if r := a.remap(ev.Rune()); r != ev.Rune() {
+			ke.Typed = string(r)
+		}
+	}
+	if !a.StartedAt.IsZero() {
+		ke.Time = a.clock.Raw().Sub(a.rawStartedAt).Seconds()
+		ke.SessionTime = a.clock.Now().Sub(a.StartedAt).Seconds()
 	}
+	a.Events = append(a.Events, ke)
+}
+
+// remap returns rune that would be typed by the same key in simulated layout
+func (a *App) remap(r rune) rune {
+	if t, ok := a.Remap[r]; ok {
+		return t
+	}
+	return r
+}
+
+func keyName(k tcell.Key) string {
+	if name, ok := tcell.KeyNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key[%d]", k)
+}
+
+// TimeIsUp returns true when duration of timed session elapsed
+func (a App) TimeIsUp() bool {
+	if a.Duration <= 0 || a.StartedAt.IsZero() {
+		return false
+	}
+	return a.clock.Now().Sub(a.StartedAt) >= a.Duration
+}
+
+// MoreTextThreshold is how many characters should be left to type,
+// before more text is requested
+const MoreTextThreshold = 200
+
+// extend appends more text to type, if it is about to end
+func (a *App) extend() {
+	if a.More == nil || len(a.Text)-a.InputPosition > MoreTextThreshold {
+		return
+	}
+	more := []rune(a.More())
+	a.Text = append(a.Text, more...)
+	a.Timeline = append(a.Timeline, make([]float64, len(more))...)
 }
 
 func log(v interface{}) {
@@ -113,7 +282,7 @@ const WPMWindow = 20
 
 func (a *App) CheckWPM() float64 {
 	wpm := 0.0
-	seconds := time.Since(a.StartedAt).Seconds()
+	seconds := a.clock.Now().Sub(a.StartedAt).Seconds()
 	if a.InputPosition > 1 {
 		secondsPerWindow := seconds - a.Timeline[max(a.InputPosition-WPMWindow, 0)]
 		wpm = wordsPerChar * float64(min(WPMWindow, a.InputPosition)) / secondsPerWindow * 60.0
@@ -121,10 +290,10 @@ func (a *App) CheckWPM() float64 {
 		if a.MinSpeed > 0 { // need to check speed limits
 			if wpm < float64(a.MinSpeed) { // speed below limit
 				if !a.LastLifeReductionTime.IsZero() { // speed was already below limit
-					diff := time.Since(a.LastLifeReductionTime)
+					diff := a.clock.Now().Sub(a.LastLifeReductionTime)
 					a.RemainingLife -= diff
 				}
-				a.LastLifeReductionTime = time.Now()
+				a.LastLifeReductionTime = a.clock.Now()
 			} else { // speed above limit, stop reductions
 				a.LastLifeReductionTime = time.Time{}
 			}
@@ -158,27 +327,79 @@ func (a *App) ToDisplay() view.DisplayableData {
 		WrongText: a.ErrorInput,
 		TODOText:  a.Text[a.InputPosition:],
 		StartedAt: a.StartedAt,
+		Now:       a.clock.Now(),
+		Duration:  a.Duration,
 		WPM:       wpm,
 		Life:      life,
 		Zen:       a.Zen,
 		Offset:    a.Offset,
+		Pass:      a.Pass.String(),
+		PassGoal:  a.passGoal(),
+		PassOK:    a.Passed(),
+		Finished:  a.finished,
+		Ghost:     a.GhostPosition(),
+		Racers:    a.racers(),
+		Countdown: a.countdown(),
+		Paused:    a.clock.Paused(),
+		Keyboard:  a.keyboard(),
+	}
+}
+
+// keyboard returns keyboard to display, with next key to type highlighted
+func (a *App) keyboard() *view.Keyboard {
+	if a.Keyboard == nil {
+		return nil
+	}
+	kb := &view.Keyboard{Layout: a.Keyboard, Heat: a.KeyHeat}
+	if len(a.ErrorInput) == 0 && a.InputPosition < len(a.Text) {
+		kb.Next = a.Text[a.InputPosition]
 	}
+	return kb
 }
 
 func (a App) Summary() string {
+	prefix := ""
+	if a.Pass != NoPass {
+		result := "failed"
+		if a.Passed() {
+			result = "passed"
+		}
+		prefix = fmt.Sprintf("%s pass %s. ", strings.Title(a.Pass.String()), result)
+	}
 	if a.InputPosition == 0 {
-		return "Typed nothing"
+		return prefix + "Typed nothing"
 	}
 	elapsed := a.Timeline[a.InputPosition-1]
 	if elapsed == 0 {
-		return "Speed of light! (actually, probably some error with timer)"
+		return prefix + "Speed of light! (actually, probably some error with timer)"
 	}
-	return fmt.Sprintf(
+	return prefix + fmt.Sprintf(
 		"Typed %d characters in %4.1f seconds. Speed: %4.1f wpm\n",
-		a.InputPosition, elapsed, float64(a.InputPosition)/elapsed*60.0/5.0,
+		a.InputPosition, elapsed, a.WPM(),
 	)
 }
 
+// WPM returns average speed for the whole typed text
+func (a App) WPM() float64 {
+	if a.InputPosition == 0 {
+		return 0
+	}
+	elapsed := a.Timeline[a.InputPosition-1]
+	if elapsed == 0 {
+		return 0
+	}
+	return float64(a.InputPosition) / elapsed * 60.0 / 5.0
+}
+
+// Accuracy returns share of correct keystrokes among all typed characters
+func (a App) Accuracy() float64 {
+	total := a.InputPosition + len(a.Mistakes)
+	if total == 0 {
+		return 1
+	}
+	return float64(total-len(a.Mistakes)) / float64(total)
+}
+
 // Compute number of typed lines
 func (a App) LinesTyped() int {
 	lt := 0
@@ -195,9 +416,17 @@ func (a App) LinesTyped() int {
 
 // Return true when should continue loop
 func (a *App) processKey(ev *tcell.EventKey) bool {
-	if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
+	if isQuit(ev.Key()) {
 		return false
 	}
+	if a.clock.Paused() {
+		a.clock.Resume()
+		return true // key that resumed session is not typed
+	}
+	if ev.Key() == PauseKey {
+		a.pause()
+		return true
+	}
 
 	switch ev.Key() {
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
@@ -208,6 +437,15 @@ func (a *App) processKey(ev *tcell.EventKey) bool {
 	return true
 }
 
+// pause stops clock, so time spent in pause is not counted in timeline,
+// speed limits or duration of session
+func (a *App) pause() {
+	if a.StartedAt.IsZero() || a.Race != nil {
+		return // nothing to pause yet, and others will not wait in race
+	}
+	a.clock.Pause()
+}
+
 func (a *App) processBackspace() {
 	if len(a.ErrorInput) == 0 {
 		return
@@ -219,15 +457,17 @@ func (a *App) processBackspace() {
 func (a *App) processCharInput(ev *tcell.EventKey) bool {
 	var ch rune
 	if ev.Key() == tcell.KeyRune {
-		ch = ev.Rune()
+		ch = a.remap(ev.Rune())
 	} else if ev.Key() == tcell.KeyEnter || ev.Key() == tcell.KeyCtrlJ {
 		ch = '\n'
 	}
-	if ch == 0 {
+	if ch == 0 || !a.raceStarted() {
 		return true
 	}
+	now := a.clock.Now()
 	if a.StartedAt.IsZero() {
-		a.StartedAt = ev.When()
+		a.StartedAt = now
+		a.rawStartedAt = a.clock.Raw()
 	}
 
 	if cheating { // always type correct :)
@@ -242,14 +482,24 @@ func (a *App) processCharInput(ev *tcell.EventKey) bool {
 		}
 		return a.InputPosition < len(a.Text)
 	}
-	if ch == a.Text[a.InputPosition] && len(a.ErrorInput) == 0 { // correct
-		a.Timeline[a.InputPosition] = ev.When().Sub(a.StartedAt).Seconds()
-		a.InputPosition++
-	} else { // wrong
-		a.ErrorInput = append(a.ErrorInput, ch)
+	correct := ch == a.Text[a.InputPosition] && len(a.ErrorInput) == 0
+	if !correct {
+		a.Mistakes = append(a.Mistakes, stats.Mistake{
+			Position: a.InputPosition,
+			Expected: string(a.Text[a.InputPosition]),
+			Typed:    string(ch),
+			Time:     now.Sub(a.StartedAt).Seconds(),
+		})
 		if !a.Mute {
 			a.scr.Beep()
 		}
 	}
+	if correct || a.Pass == FastPass { // errors are ignored in fast pass
+		a.Timeline[a.InputPosition] = now.Sub(a.StartedAt).Seconds()
+		a.InputPosition++
+		a.extend()
+	} else {
+		a.ErrorInput = append(a.ErrorInput, ch)
+	}
 	return a.InputPosition < len(a.Text)
 }
@@ -0,0 +1,454 @@
+package app
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bunyk/gokeybr/fs"
+	"github.com/bunyk/gokeybr/layout"
+	"github.com/bunyk/gokeybr/stats"
+	"github.com/bunyk/gokeybr/view"
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestMain makes sure tests do not touch stats of user
+func TestMain(m *testing.M) {
+	home, err := ioutil.TempDir("", "gokeybr-test")
+	if err != nil {
+		panic(err)
+	}
+	fs.DataDir = home
+	stats.SetStore(stats.NewMemoryStore())
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// script types scripted input into app running on simulation screen,
+// with time measured by fake clock
+type script struct {
+	t     *testing.T
+	app   *App
+	clock *fakeClock
+	scr   tcell.SimulationScreen
+	ended bool
+}
+
+func newScript(t *testing.T, text string) *script {
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	scr := tcell.NewSimulationScreen("UTF-8")
+	a, err := NewWithScreen(text, scr, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scr.SetSize(40, 10)
+	return &script{t: t, app: a, clock: clock, scr: scr}
+}
+
+// typeKeys presses keys one by one, waiting interval before each.
+// '\b' is backspace, '\x1b' is escape, '\x10' is PauseKey.
+func (s *script) typeKeys(keys string, interval time.Duration) {
+	for _, r := range keys {
+		if s.ended {
+			return
+		}
+		s.clock.now = s.clock.now.Add(interval)
+		var ev *tcell.EventKey
+		switch r {
+		case '\b':
+			ev = tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone)
+		case '\x1b':
+			ev = tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone)
+		case '\x10':
+			ev = tcell.NewEventKey(PauseKey, 0, tcell.ModNone)
+		case '\n':
+			ev = tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+		default:
+			ev = tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)
+		}
+		s.ended = !s.app.handle(ev)
+		s.render()
+	}
+}
+
+// render draws app on screen, like main loop does after each event
+func (s *script) render() {
+	view.Render(s.scr, s.app.ToDisplay())
+	s.ended = s.ended || s.app.over()
+}
+
+// line returns text displayed in row y of screen
+func (s *script) line(y int) string {
+	cells, w, _ := s.scr.GetContents()
+	var sb strings.Builder
+	for _, c := range cells[y*w : (y+1)*w] {
+		sb.WriteRune(c.Runes[0])
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+func TestTypingSession(t *testing.T) {
+	s := newScript(t, "hello")
+	s.typeKeys("hello", 200*time.Millisecond)
+
+	if !s.ended {
+		t.Errorf("Session should end when text is typed")
+	}
+	if s.app.InputPosition != 5 {
+		t.Errorf("Expected to type 5 characters, typed %d", s.app.InputPosition)
+	}
+	expected := []float64{0, 0.2, 0.4, 0.6, 0.8}
+	for i, v := range expected {
+		if diff := s.app.Timeline[i] - v; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Expected timeline %v, got %v", expected, s.app.Timeline)
+			break
+		}
+	}
+	if wpm := s.app.WPM(); wpm < 74.9 || wpm > 75.1 { // 5 characters in 0.8 seconds
+		t.Errorf("Expected speed 75 wpm, got %.2f", wpm)
+	}
+}
+
+func TestMistakesShouldBeCorrected(t *testing.T) {
+	s := newScript(t, "the cat")
+	s.typeKeys("tje", 100*time.Millisecond)
+	if s.app.InputPosition != 1 {
+		t.Errorf("Should not go further after mistake, but position is %d", s.app.InputPosition)
+	}
+	if string(s.app.ErrorInput) != "je" {
+		t.Errorf("Expected error input %#v, got %#v", "je", string(s.app.ErrorInput))
+	}
+	s.typeKeys("\b\bhe", 100*time.Millisecond)
+	if s.app.InputPosition != 3 {
+		t.Errorf("Expected to continue after correction, but position is %d", s.app.InputPosition)
+	}
+	if len(s.app.Mistakes) != 2 {
+		t.Fatalf("Expected 2 mistakes, got %+v", s.app.Mistakes)
+	}
+	m := s.app.Mistakes[0]
+	if m.Position != 1 || m.Expected != "h" || m.Typed != "j" {
+		t.Errorf("Unexpected first mistake %+v", m)
+	}
+	s.typeKeys("\x1b", 100*time.Millisecond)
+	if !s.ended {
+		t.Errorf("Escape should end session")
+	}
+}
+
+func TestLifeReduction(t *testing.T) {
+	s := newScript(t, strings.Repeat("a", 50))
+	s.app.MinSpeed = 100
+	s.typeKeys(strings.Repeat("a", 50), time.Second) // 12 wpm
+	if !s.ended {
+		t.Errorf("Session should end when life is over")
+	}
+	if s.app.RemainingLife > 0 {
+		t.Errorf("Expected life to be over, but %s remains", s.app.RemainingLife)
+	}
+	if s.app.InputPosition >= 50 {
+		t.Errorf("Session should end before text is typed")
+	}
+}
+
+func TestTimedSession(t *testing.T) {
+	s := newScript(t, "abc")
+	s.app.Duration = time.Second
+	s.app.More = func() string { return "abc" }
+	s.typeKeys(strings.Repeat("abc", 10), 200*time.Millisecond)
+	// first key starts session, next five are typed in one second
+	if s.app.InputPosition != 6 {
+		t.Errorf("Expected to type 6 characters in a second, typed %d", s.app.InputPosition)
+	}
+}
+
+func TestPause(t *testing.T) {
+	s := newScript(t, "abc")
+	s.typeKeys("ab\x10", 100*time.Millisecond)
+	if got := s.line(3); got != "  Paused, press any key to continue" {
+		t.Errorf("Expected text to be hidden in pause, got %#v", got)
+	}
+	s.typeKeys("x", time.Minute) // resumes, and is not typed
+	s.typeKeys("c", 100*time.Millisecond)
+	if len(s.app.Mistakes) != 0 {
+		t.Errorf("Key that resumes session should not be typed, got mistakes %+v", s.app.Mistakes)
+	}
+	if got := s.app.Timeline[2]; got < 0.299 || got > 0.301 {
+		t.Errorf("Expected pause not to be counted in timeline, got %v", s.app.Timeline)
+	}
+}
+
+func TestIdleTrimming(t *testing.T) {
+	s := newScript(t, "abcd")
+	s.app.IdleLimit = time.Second
+	s.typeKeys("ab", 100*time.Millisecond)
+	s.typeKeys("c", time.Minute)
+	s.typeKeys("d", 100*time.Millisecond)
+	expected := []float64{0, 0.1, 1.1, 1.2}
+	for i, v := range expected {
+		if diff := s.app.Timeline[i] - v; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Expected idle time to be cut to a second, timeline %v, got %v", expected, s.app.Timeline)
+			break
+		}
+	}
+	if len(s.app.Gaps) != 1 || s.app.Gaps[0].Position != 2 || s.app.Gaps[0].Duration != 60 {
+		t.Errorf("Expected gap of 60 seconds before third character to be recorded, got %+v", s.app.Gaps)
+	}
+}
+
+func TestEventsAreRecordedWithRawTime(t *testing.T) {
+	s := newScript(t, "abcd")
+	s.app.RecordEvents = true
+	s.app.IdleLimit = time.Second
+	s.typeKeys("ab\x10", 100*time.Millisecond)
+	s.typeKeys("x", time.Minute) // resumes
+	s.typeKeys("c", time.Minute)
+	s.typeKeys("d", 100*time.Millisecond)
+	if len(s.app.Events) != 4 {
+		t.Fatalf("Expected only typed keys to be recorded, got %+v", s.app.Events)
+	}
+	expected := []struct{ raw, session float64 }{{0, 0}, {0.1, 0.1}, {120.2, 1.2}, {120.3, 1.3}}
+	for i, e := range expected {
+		ev := s.app.Events[i]
+		if math.Abs(ev.Time-e.raw) > 1e-9 || math.Abs(ev.SessionTime-e.session) > 1e-9 {
+			t.Errorf("Expected event %d at %v seconds, %v by session clock, got %+v", i, e.raw, e.session, ev)
+		}
+	}
+}
+
+func TestRendering(t *testing.T) {
+	s := newScript(t, "hello world")
+	s.render()
+	if got := s.line(1); got != "  Type this:" {
+		t.Errorf("Expected header, got %#v", got)
+	}
+	s.typeKeys("hex", 100*time.Millisecond)
+	if got := s.line(3); got != "  hexllo␣world" {
+		t.Errorf("Expected text with typed error, got %#v", got)
+	}
+	if got := s.line(9); !strings.Contains(got, "0.2 sec") {
+		t.Errorf("Expected timer to show time by app clock, got %#v", got)
+	}
+
+	s = newScript(t, "hello world")
+	s.app.Zen = true
+	s.render()
+	if got := s.line(1); got != "" {
+		t.Errorf("Header should not be shown in zen mode, got %#v", got)
+	}
+}
+
+func TestKeyboard(t *testing.T) {
+	s := newScript(t, "hello")
+	s.scr.SetSize(60, 16)
+	s.app.Keyboard, _ = layout.Get("qwerty")
+	s.typeKeys("h", 100*time.Millisecond)
+	if got := s.line(11); got != "     q   w  [e]  r   t   y   u   i   o   p   [   ]   \\" {
+		t.Errorf("Expected next key to be highlighted, got %#v", got)
+	}
+	if got := s.line(14); strings.TrimSpace(got) != "space" {
+		t.Errorf("Expected space bar under keyboard, got %#v", got)
+	}
+}
+
+func TestRemap(t *testing.T) {
+	s := newScript(t, "arst")
+	s.app.Remap = map[rune]rune{'s': 'r', 'd': 's', 'f': 't'}
+	s.app.RecordEvents = true
+	s.typeKeys("asdf", 100*time.Millisecond)
+	if !s.ended || len(s.app.Mistakes) != 0 {
+		t.Errorf("Expected keys to be remapped, got position %d and mistakes %+v", s.app.InputPosition, s.app.Mistakes)
+	}
+	if ev := s.app.Events; len(ev) != 4 || ev[0].Rune != "a" || ev[0].Typed != "" || ev[1].Rune != "s" || ev[1].Typed != "r" {
+		t.Errorf("Expected raw keys to be recorded with remapped characters, got %+v", ev)
+	}
+	a := replay(t, stats.Session{Text: s.app.Text, Timeline: s.app.Timeline, Events: s.app.Events})
+	if a.InputPosition != 4 || len(a.Mistakes) != 0 {
+		t.Errorf("Expected remapped characters to be replayed, got position %d and mistakes %+v", a.InputPosition, a.Mistakes)
+	}
+}
+
+func TestSlowPass(t *testing.T) {
+	s := newScript(t, "abc")
+	s.app.Pass = SlowPass
+	s.typeKeys("ax\bbc", time.Second)
+	if !s.ended || s.app.Passed() {
+		t.Errorf("Slow pass should not be passed with error, got mistakes %+v", s.app.Mistakes)
+	}
+	s = newScript(t, "abc")
+	s.app.Pass = SlowPass
+	s.typeKeys("abc", time.Second)
+	if !s.app.Passed() {
+		t.Errorf("Slow pass should be passed without errors, even when slow")
+	}
+}
+
+func TestFastPass(t *testing.T) {
+	s := newScript(t, "abc")
+	s.app.Pass = FastPass
+	s.app.TargetWPM = 60
+	s.typeKeys("axc", 100*time.Millisecond)
+	if !s.ended || s.app.InputPosition != 3 || len(s.app.Mistakes) != 1 {
+		t.Errorf("Errors should be typed over in fast pass, got position %d and mistakes %+v", s.app.InputPosition, s.app.Mistakes)
+	}
+	if !s.app.Passed() { // 3 characters in 0.2 seconds
+		t.Errorf("Fast pass should be passed with %.0f wpm, when target is 60", s.app.WPM())
+	}
+	s = newScript(t, "abc")
+	s.app.Pass = FastPass
+	s.app.TargetWPM = 60
+	s.typeKeys("abc", time.Second)
+	if s.app.Passed() {
+		t.Errorf("Fast pass should not be passed with %.0f wpm, when target is 60", s.app.WPM())
+	}
+}
+
+func TestNormalPass(t *testing.T) {
+	s := newScript(t, "abcd")
+	s.app.Pass = NormalPass
+	s.app.TargetWPM = 60
+	s.typeKeys("abx\bcd", 100*time.Millisecond)
+	if s.app.Passed() {
+		t.Errorf("Normal pass should not be passed with accuracy %.2f", s.app.Accuracy())
+	}
+	s = newScript(t, "abcd")
+	s.app.Pass = NormalPass
+	s.app.TargetWPM = 60
+	s.typeKeys("abcd", 100*time.Millisecond)
+	if !s.app.Passed() {
+		t.Errorf("Normal pass should be passed with %.0f wpm without errors", s.app.WPM())
+	}
+}
+
+func TestDrillPassesKeepSettings(t *testing.T) {
+	base := newScript(t, "")
+	a := base.app
+	a.Zen = true
+	a.MinSpeed = 30
+	a.Duration = time.Minute
+	a.IdleLimit = 5 * time.Second
+	a.RecordEvents = true
+	a.GhostTimeline = []float64{0, 0.5, 0.7, 1.2}
+	a.GhostWPM = 40
+	a.Remap = map[rune]rune{'s': 'r'}
+	for _, pass := range drillPasses {
+		s := &script{t: t, app: a.next("ab", pass), clock: base.clock, scr: base.scr}
+		if s.app.Pass != pass || !s.app.Zen || s.app.MinSpeed != 30 || s.app.Duration != time.Minute ||
+			s.app.IdleLimit != 5*time.Second || !s.app.RecordEvents || s.app.GhostTimeline != nil ||
+			s.app.GhostWPM != 40 || s.app.Remap['s'] != 'r' {
+			t.Errorf("Expected settings to be kept in %s pass, got %+v", pass, s.app)
+		}
+		s.typeKeys("ab", 100*time.Millisecond)
+		if !s.ended || len(s.app.Events) != 2 || s.app.Timeline[1] < 0.099 {
+			t.Errorf("Expected line to be typed in %s pass with events recorded, got %+v, timeline %v", pass, s.app.Events, s.app.Timeline)
+		}
+	}
+}
+
+// replay runs replay of session on simulation screen, many times faster,
+// and returns app after replay ended
+func replay(t *testing.T, s stats.Session) *App {
+	scr := tcell.NewSimulationScreen("UTF-8")
+	a, err := NewWithScreen(string(s.Text), scr, RealClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scr.SetSize(40, 10)
+	a.Zen = true
+	done := make(chan bool)
+	go func() {
+		done <- a.Replay(s, 100)
+	}()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case <-done:
+			return a
+		case <-timeout:
+			t.Fatalf("Replay did not end, typed %d characters", a.InputPosition)
+		case <-time.After(10 * time.Millisecond):
+			scr.InjectKey(tcell.KeyRune, 'x', tcell.ModNone) // to close results when replay ends
+		}
+	}
+}
+
+func TestReplayOfTimeline(t *testing.T) {
+	a := replay(t, stats.Session{
+		Text:     []rune("abc"),
+		Timeline: []float64{0, 1, 2},
+		Mistakes: []stats.Mistake{{Position: 1, Expected: "b", Typed: "x", Time: 0.5}},
+	})
+	if a.InputPosition != 3 || len(a.Mistakes) != 1 || a.Mistakes[0].Typed != "x" {
+		t.Errorf("Expected text to be typed with a mistake, got position %d and mistakes %+v", a.InputPosition, a.Mistakes)
+	}
+	if a.Timeline[2] < 2 {
+		t.Errorf("Expected replay to go in time of session, got timeline %v", a.Timeline)
+	}
+}
+
+func TestReplayDoesNotUseCurrentBindings(t *testing.T) {
+	// Ctrl-P was not bound to pause when session was recorded, so it did nothing
+	a := replay(t, stats.Session{
+		Text:     []rune("abc"),
+		Timeline: []float64{0, 0.5, 1},
+		Events: []stats.KeyEvent{
+			{Key: "Rune", Rune: "a"},
+			{Key: "Ctrl-P", Time: 0.2, SessionTime: 0.2, Position: 1},
+			{Key: "Rune", Rune: "x", Time: 0.3, SessionTime: 0.3, Position: 1},
+			{Key: "Backspace2", Time: 0.4, SessionTime: 0.4, Position: 1},
+			{Key: "Rune", Rune: "b", Time: 0.5, SessionTime: 0.5, Position: 2},
+			{Key: "Rune", Rune: "c", Time: 1, SessionTime: 1, Position: 3},
+		},
+	})
+	if a.clock.Paused() || a.InputPosition != 3 || len(a.Mistakes) != 1 {
+		t.Errorf("Expected recorded keys to be replayed, got position %d and mistakes %+v", a.InputPosition, a.Mistakes)
+	}
+}
+
+func TestGhostPacing(t *testing.T) {
+	s := newScript(t, "abcdef")
+	s.app.GhostWPM = 60 // a character in 0.2 seconds
+	if pos := s.app.GhostPosition(); pos != -1 {
+		t.Errorf("Ghost should not start before session, got position %d", pos)
+	}
+	s.typeKeys("ab", 100*time.Millisecond)
+	if pos := s.app.GhostPosition(); pos != 1 { // first character at 0 seconds, second at 0.2
+		t.Errorf("Expected ghost to type 1 character in 0.1 seconds, got %d", pos)
+	}
+	s.typeKeys("c", 400*time.Millisecond)
+	if pos := s.app.GhostPosition(); pos != 3 {
+		t.Errorf("Expected ghost to type 3 characters in 0.5 seconds, got %d", pos)
+	}
+	cells, w, _ := s.scr.GetContents()
+	ghost, next := cells[3*w+2+3], cells[3*w+2+4]
+	if ghost.Runes[0] != 'd' || ghost.Style == next.Style {
+		t.Errorf("Expected character %q under ghost to be highlighted", ghost.Runes[0])
+	}
+	s.typeKeys("def", 100*time.Millisecond)
+	if got := s.app.GhostSummary(); got != "You were 0.2 seconds ahead of the ghost" {
+		t.Errorf("Expected to finish 0.2 seconds before ghost, got %#v", got)
+	}
+
+	s = newScript(t, "abcdef")
+	s.app.GhostTimeline = []float64{0, 0.3, 0.35, 1, 1.1, 1.2}
+	s.typeKeys("a", 100*time.Millisecond)
+	s.typeKeys("b", 500*time.Millisecond)
+	if pos := s.app.GhostPosition(); pos != 3 {
+		t.Errorf("Expected ghost to type 3 characters of its timeline in 0.5 seconds, got %d", pos)
+	}
+	s.typeKeys("cdef", 100*time.Millisecond)
+	if got := s.app.GhostSummary(); got != "You were 0.3 seconds ahead of the ghost" {
+		t.Errorf("Expected to finish 0.3 seconds before ghost, got %#v", got)
+	}
+}
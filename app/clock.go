@@ -0,0 +1,77 @@
+package app
+
+import "time"
+
+// Clock gives current time to app and view, so it could be paused,
+// sped up for replays, or replaced in tests
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is Clock that gives system time
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// PausableClock is Clock that could be stopped, so time spent in pause is not counted
+type PausableClock struct {
+	clock    Clock
+	pausedAt time.Time     // zero when running
+	paused   time.Duration // total duration of finished pauses
+}
+
+func NewPausableClock(c Clock) *PausableClock {
+	return &PausableClock{clock: c}
+}
+
+func (c *PausableClock) Now() time.Time {
+	if c.Paused() {
+		return c.pausedAt.Add(-c.paused)
+	}
+	return c.clock.Now().Add(-c.paused)
+}
+
+func (c *PausableClock) Pause() {
+	if !c.Paused() {
+		c.pausedAt = c.clock.Now()
+	}
+}
+
+func (c *PausableClock) Resume() {
+	if c.Paused() {
+		c.paused += c.clock.Now().Sub(c.pausedAt)
+		c.pausedAt = time.Time{}
+	}
+}
+
+// Skip excludes d from time measured by clock, as if clock was paused for that long
+func (c *PausableClock) Skip(d time.Duration) {
+	c.paused += d
+}
+
+// Raw returns time of underlying clock, with pauses and skipped time
+func (c *PausableClock) Raw() time.Time {
+	return c.clock.Now()
+}
+
+func (c *PausableClock) Paused() bool {
+	return !c.pausedAt.IsZero()
+}
+
+// ScaledClock is Clock that goes speed times faster than another clock
+type ScaledClock struct {
+	clock Clock
+	start time.Time
+	speed float64
+}
+
+func NewScaledClock(c Clock, speed float64) *ScaledClock {
+	return &ScaledClock{clock: c, start: c.Now(), speed: speed}
+}
+
+func (c *ScaledClock) Now() time.Time {
+	elapsed := c.clock.Now().Sub(c.start)
+	return c.start.Add(time.Duration(float64(elapsed) * c.speed))
+}
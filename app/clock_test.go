@@ -0,0 +1,34 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPausableClock(t *testing.T) {
+	fc := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	start := fc.Now()
+	c := NewPausableClock(fc)
+
+	fc.now = fc.now.Add(time.Second)
+	c.Pause()
+	fc.now = fc.now.Add(time.Minute)
+	if got := c.Now().Sub(start); got != time.Second {
+		t.Errorf("Paused clock should stay at 1s, got %s", got)
+	}
+	c.Resume()
+	fc.now = fc.now.Add(time.Second)
+	if got := c.Now().Sub(start); got != 2*time.Second {
+		t.Errorf("Time in pause should not count, expected 2s, got %s", got)
+	}
+}
+
+func TestScaledClock(t *testing.T) {
+	fc := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	start := fc.Now()
+	c := NewScaledClock(fc, 2)
+	fc.now = fc.now.Add(time.Second)
+	if got := c.Now().Sub(start); got != 2*time.Second {
+		t.Errorf("Clock two times faster should show 2s, got %s", got)
+	}
+}
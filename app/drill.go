@@ -0,0 +1,143 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bunyk/gokeybr/view"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Pass is a way to type line in drill, like in gotypist:
+// each line is typed slow (without errors), fast (ignoring errors)
+// and normal (fast and without errors at the same time).
+type Pass int
+
+const (
+	NoPass Pass = iota // regular session, not a drill
+	SlowPass
+	FastPass
+	NormalPass
+)
+
+var drillPasses = []Pass{SlowPass, FastPass, NormalPass}
+
+func (p Pass) String() string {
+	switch p {
+	case SlowPass:
+		return "slow"
+	case FastPass:
+		return "fast"
+	case NormalPass:
+		return "normal"
+	}
+	return ""
+}
+
+// ParsePass returns pass by its name, NoPass for unknown names
+func ParsePass(name string) Pass {
+	for _, p := range drillPasses {
+		if p.String() == name {
+			return p
+		}
+	}
+	return NoPass
+}
+
+// FastPassRatio is how much faster than normal target fast pass should be typed
+const FastPassRatio = 1.2
+
+// NormalPassAccuracy is minimal accuracy required to succeed in normal pass
+const NormalPassAccuracy = 0.95
+
+// Passed returns whether target of the pass is met by what was typed so far
+func (a App) Passed() bool {
+	switch a.Pass {
+	case SlowPass:
+		return len(a.Mistakes) == 0
+	case FastPass:
+		return a.WPM() >= a.TargetWPM
+	case NormalPass:
+		return a.WPM() >= a.TargetWPM && a.Accuracy() >= NormalPassAccuracy
+	}
+	return true
+}
+
+func (a App) passGoal() string {
+	switch a.Pass {
+	case SlowPass:
+		return "no errors"
+	case FastPass:
+		return fmt.Sprintf("%.0f wpm, errors ignored", a.TargetWPM)
+	case NormalPass:
+		return fmt.Sprintf("%.0f wpm, %.0f%% accuracy", a.TargetWPM, NormalPassAccuracy*100)
+	}
+	return ""
+}
+
+// RunDrill makes user type each line three times, in slow, fast and normal pass.
+// targetWPM is speed required for normal pass, fast pass requires FastPassRatio times more.
+// Returned sessions are not saved, so that could be done after screen is closed.
+// linesTyped is number of lines, for which all passes were finished.
+func (a *App) RunDrill(lines []string, targetWPM float64) (sessions []*App, linesTyped int) {
+	defer a.scr.Fini()
+	a.startEvents()
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			linesTyped = i + 1
+			continue // nothing to type
+		}
+		for _, pass := range drillPasses {
+			s := a.next(line, pass)
+			s.TargetWPM = targetWPM
+			if pass == FastPass {
+				s.TargetWPM *= FastPassRatio
+			}
+			finished := s.loop()
+			sessions = append(sessions, s)
+			if !finished || !s.waitKey() {
+				return
+			}
+		}
+		linesTyped = i + 1
+	}
+	return
+}
+
+// next creates new session with given text, that shares screen and settings.
+// Ghost timeline is not shared, as it is recorded for other text, only ghost speed is.
+func (a *App) next(text string, pass Pass) *App {
+	s := &App{
+		Text:          []rune(text),
+		ErrorInput:    make([]rune, 0, 20),
+		RemainingLife: InitialLife,
+		Zen:           a.Zen,
+		Mute:          a.Mute,
+		MinSpeed:      a.MinSpeed,
+		Duration:      a.Duration,
+		RecordEvents:  a.RecordEvents,
+		IdleLimit:     a.IdleLimit,
+		GhostWPM:      a.GhostWPM,
+		Keyboard:      a.Keyboard,
+		KeyHeat:       a.KeyHeat,
+		Remap:         a.Remap,
+		Pass:          pass,
+		scr:           a.scr,
+		clock:         a.clock,
+		events:        a.events,
+	}
+	s.Timeline = make([]float64, len(s.Text))
+	return s
+}
+
+// waitKey shows result of finished session until any key is pressed.
+// Returns false when user asked to quit.
+func (a *App) waitKey() bool {
+	a.finished = true
+	for {
+		view.Render(a.scr, a.ToDisplay())
+		if ev, ok := (<-a.events).(*tcell.EventKey); ok {
+			return !isQuit(ev.Key())
+		}
+	}
+}
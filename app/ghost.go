@@ -0,0 +1,64 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// GhostPosition returns how much of text ghost had typed by now,
+// or -1 when there is no ghost or session is not started yet
+func (a App) GhostPosition() int {
+	if a.GhostTimeline == nil && a.GhostWPM <= 0 || a.StartedAt.IsZero() {
+		return -1
+	}
+	return a.ghostPositionAt(a.clock.Now().Sub(a.StartedAt).Seconds())
+}
+
+// ghostPositionAt returns number of characters ghost typed in given seconds since start
+func (a App) ghostPositionAt(seconds float64) int {
+	if a.GhostTimeline != nil {
+		return sort.Search(len(a.GhostTimeline), func(i int) bool {
+			return a.GhostTimeline[i] > seconds
+		})
+	}
+	// first character is typed at second 0, as in real timeline
+	pos := int(seconds/a.ghostCharDuration()) + 1
+	return min(pos, len(a.Text))
+}
+
+// ghostTimeOf returns second at which ghost typed character at position i,
+// false if ghost never typed it
+func (a App) ghostTimeOf(i int) (float64, bool) {
+	if a.GhostTimeline != nil {
+		if i >= len(a.GhostTimeline) {
+			return 0, false
+		}
+		return a.GhostTimeline[i], true
+	}
+	return float64(i) * a.ghostCharDuration(), true
+}
+
+func (a App) ghostCharDuration() float64 {
+	return 60.0 / (a.GhostWPM / wordsPerChar)
+}
+
+// GhostSummary tells how far ahead or behind the ghost user finished
+func (a App) GhostSummary() string {
+	if a.GhostTimeline == nil && a.GhostWPM <= 0 || a.InputPosition == 0 {
+		return ""
+	}
+	last := a.InputPosition - 1
+	ghostTime, ok := a.ghostTimeOf(last)
+	if !ok {
+		return "Ghost did not type that far"
+	}
+	diff := ghostTime - a.Timeline[last]
+	if math.Abs(diff) < 0.05 {
+		return "Tie with the ghost!"
+	}
+	if diff > 0 {
+		return fmt.Sprintf("You were %.1f seconds ahead of the ghost", diff)
+	}
+	return fmt.Sprintf("You were %.1f seconds behind the ghost", -diff)
+}
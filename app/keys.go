@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// PauseKey stops clock and hides text until any other key is pressed
+var PauseKey = tcell.KeyCtrlP
+
+// QuitKey ends session. Ctrl+C also always does.
+var QuitKey = tcell.KeyEscape
+
+func isQuit(k tcell.Key) bool {
+	return k == QuitKey || k == tcell.KeyCtrlC
+}
+
+// keyByName returns key by its name in tcell.KeyNames, like "Ctrl-P" or "Esc"
+func keyByName(name string) (tcell.Key, bool) {
+	for k, n := range tcell.KeyNames {
+		if n == name {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// Bindings lists actions which keys could be changed
+var Bindings = map[string]*tcell.Key{
+	"pause": &PauseKey,
+	"quit":  &QuitKey,
+}
+
+// SetKey binds action to key with given name
+func SetKey(action, name string) error {
+	k, ok := Bindings[action]
+	if !ok {
+		return fmt.Errorf("Unknown action %s, only pause and quit keys could be set", action)
+	}
+	key, ok := keyByName(name)
+	if !ok || key == tcell.KeyRune {
+		return fmt.Errorf("Unknown key %s, expected names like Ctrl-P, Esc or F1", name)
+	}
+	*k = key
+	return nil
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"github.com/bunyk/gokeybr/race"
+	"github.com/bunyk/gokeybr/view"
+	"github.com/gdamore/tcell/v2"
+)
+
+// RunRace runs session as part of race, and then shows results
+// until any key is pressed. Returns false when user quit before finishing the text.
+func (a *App) RunRace() bool {
+	defer a.scr.Fini()
+	a.startEvents()
+	if !a.loop() {
+		return false
+	}
+	a.finished = true
+	for {
+		view.Render(a.scr, a.ToDisplay())
+		if _, ok := (<-a.events).(*tcell.EventKey); ok {
+			return true
+		}
+	}
+}
+
+// raceStarted returns false during countdown
+func (a App) raceStarted() bool {
+	return a.Race == nil || !a.clock.Now().Before(a.RaceStart)
+}
+
+// reportProgress sends input position to other racers, when it changed
+func (a *App) reportProgress() {
+	if a.Race == nil || a.InputPosition == a.reportedPosition {
+		return
+	}
+	a.reportedPosition = a.InputPosition
+	finished := a.InputPosition == len(a.Text)
+	seconds := 0.0
+	if finished { // measured from start of race, so late start is not faster
+		seconds = a.clock.Now().Sub(a.RaceStart).Seconds()
+	}
+	_ = a.Race.Report(a.InputPosition, finished, seconds) // lost connection is not a reason to stop typing
+}
+
+func (a App) racers() []view.Racer {
+	if a.Race == nil {
+		return nil
+	}
+	standings := race.Standings(a.Race.Racers())
+	res := make([]view.Racer, len(standings))
+	for i, r := range standings {
+		res[i] = view.Racer{
+			Name:     r.Name,
+			Progress: float64(r.Position) / float64(len(a.Text)),
+			Place:    r.Place,
+			Time:     r.Time,
+			Left:     r.Left,
+			You:      r.Name == a.Race.Name,
+		}
+	}
+	return res
+}
+
+func (a App) countdown() float64 {
+	if a.raceStarted() {
+		return 0
+	}
+	return a.RaceStart.Sub(a.clock.Now()).Seconds()
+}
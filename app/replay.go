@@ -0,0 +1,149 @@
+package app
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bunyk/gokeybr/stats"
+	"github.com/bunyk/gokeybr/view"
+	"github.com/gdamore/tcell/v2"
+)
+
+// replayKey is key press that should be replayed at given second of session
+type replayKey struct {
+	at   float64
+	key  tcell.Key
+	r    rune
+	mods tcell.ModMask
+}
+
+// Replay shows how session was typed, speed times faster than it was.
+// Returns false when user interrupted replay.
+func (a *App) Replay(s stats.Session, speed float64) bool {
+	defer a.scr.Fini()
+	a.startEvents()
+	a.Pass = ParsePass(s.Mode)
+	a.Mute = true
+
+	// Replay goes in time of session, so timer and speed are displayed as they were
+	a.clock = NewPausableClock(NewScaledClock(a.clock, speed))
+	keys := replayKeys(s)
+	start := a.clock.Now()
+	for _, k := range keys {
+		due := start.Add(time.Duration(k.at * float64(time.Second)))
+		for a.clock.Now().Before(due) {
+			view.Render(a.scr, a.ToDisplay())
+			wait := time.Duration(float64(due.Sub(a.clock.Now())) / speed)
+			select {
+			case ev := <-a.events:
+				if !a.processReplayEvent(ev) {
+					return false
+				}
+			case <-time.After(wait):
+			}
+		}
+		if k.key == tcell.KeyRune && a.InputPosition >= len(a.Text) {
+			continue // keys typed after end of saved text
+		}
+		a.typeKey(tcell.NewEventKey(k.key, k.r, k.mods))
+	}
+	return a.waitKey()
+}
+
+// typeKey types or erases character, like processKey does for keys that are not bound to actions.
+// Bindings could be different when session was recorded, so they are not used in replay.
+func (a *App) typeKey(ev *tcell.EventKey) {
+	if ev.Key() == tcell.KeyBackspace || ev.Key() == tcell.KeyBackspace2 {
+		a.processBackspace()
+		return
+	}
+	a.processCharInput(ev)
+}
+
+// processReplayEvent handles events from user while replay is running.
+// Returns false when user asked to quit.
+func (a *App) processReplayEvent(ev tcell.Event) bool {
+	switch event := ev.(type) {
+	case *tcell.EventKey:
+		return !isQuit(event.Key())
+	case *tcell.EventResize:
+		a.scr.Sync()
+	}
+	return true
+}
+
+// replayKeys returns key presses to replay session.
+// When raw events were not recorded, they are reconstructed from timeline and mistakes.
+func replayKeys(s stats.Session) []replayKey {
+	if len(s.Events) > 0 {
+		return eventKeys(s.Events)
+	}
+	keys := make([]replayKey, 0, len(s.Text)+2*len(s.Mistakes))
+	for i, r := range s.Text {
+		keys = append(keys, replayKey{at: s.Timeline[i], key: tcell.KeyRune, r: r})
+	}
+	for _, m := range s.Mistakes {
+		if m.Typed == "" {
+			continue
+		}
+		typed := []rune(m.Typed)[0]
+		if ParsePass(s.Mode) == FastPass { // mistake was typed instead of correct character
+			if m.Position < len(keys) {
+				keys[m.Position].r = typed
+			}
+			continue
+		}
+		keys = append(keys, replayKey{at: m.Time, key: tcell.KeyRune, r: typed})
+		if m.Position < len(s.Text) { // mistake was corrected before typing correct character
+			keys = append(keys, replayKey{at: s.Timeline[m.Position], key: tcell.KeyBackspace2})
+		}
+	}
+	for i := range keys {
+		if keys[i].r == '\n' {
+			keys[i].key, keys[i].r = tcell.KeyEnter, 0
+		}
+	}
+	// Backspaces go before correct character typed at the same time
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].at == keys[j].at {
+			return keys[i].key == tcell.KeyBackspace2 && keys[j].key != tcell.KeyBackspace2
+		}
+		return keys[i].at < keys[j].at
+	})
+	return keys
+}
+
+// typingKey returns whether key types or erases character
+func typingKey(k tcell.Key) bool {
+	switch k {
+	case tcell.KeyRune, tcell.KeyEnter, tcell.KeyCtrlJ, tcell.KeyBackspace, tcell.KeyBackspace2:
+		return true
+	}
+	return false
+}
+
+func eventKeys(events []stats.KeyEvent) []replayKey {
+	keys := make([]replayKey, 0, len(events))
+	for _, e := range events {
+		// replay goes by clock of session, events recorded before it was saved separately have it in Time
+		at := e.SessionTime
+		if at == 0 {
+			at = e.Time
+		}
+		k := replayKey{at: at, mods: tcell.ModMask(e.Modifiers)}
+		if e.Typed != "" { // replayed without remapping, as layout could be other now
+			k.key, k.r = tcell.KeyRune, []rune(e.Typed)[0]
+		} else if e.Rune != "" {
+			k.key, k.r = tcell.KeyRune, []rune(e.Rune)[0]
+		} else if key, ok := keyByName(e.Key); ok {
+			k.key = key
+		} else {
+			continue
+		}
+		if !typingKey(k.key) {
+			continue // like key that ended session, which could be bound differently now
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bunyk/gokeybr/app"
+	"github.com/bunyk/gokeybr/fs"
+	"github.com/bunyk/gokeybr/view"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ConfigFile keeps defaults for flags, colors and key bindings, in config directory. It is shared by all profiles.
+const ConfigFile = "config.json"
+
+type config struct {
+	Flags    map[string]string            `json:"flags,omitempty"`    // persistent flags, like "zen"
+	Commands map[string]map[string]string `json:"commands,omitempty"` // flags of commands, like "words": {"number": "20"}
+	Colors   map[string]string            `json:"colors,omitempty"`
+	Keys     map[string]string            `json:"keys,omitempty"`
+}
+
+var cfg config
+
+func loadConfig() error {
+	err := fs.LoadConfigJSON(ConfigFile, &cfg)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// commandKey returns name of command in config, like "words" or "race-host"
+func commandKey(cmd *cobra.Command) string {
+	return strings.Join(strings.Fields(cmd.CommandPath())[1:], "-")
+}
+
+// applyConfig sets flags of command that were not given in command line to values from config,
+// and changes colors and key bindings
+func applyConfig(cmd *cobra.Command) error {
+	apply := func(values map[string]string) error {
+		for name, value := range values {
+			f := cmd.Flags().Lookup(name)
+			if f == nil || f.Changed {
+				continue
+			}
+			if name == "profile" && os.Getenv(fs.ProfileEnv) != "" {
+				continue // environment overrides config
+			}
+			if name == "data-dir" {
+				continue // config is already loaded from it
+			}
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("Invalid value of %s in config: %s", name, err)
+			}
+		}
+		return nil
+	}
+	if err := apply(cfg.Flags); err != nil {
+		return err
+	}
+	if err := apply(cfg.Commands[commandKey(cmd)]); err != nil {
+		return err
+	}
+	for element, color := range cfg.Colors {
+		if err := view.SetColor(element, color); err != nil {
+			return err
+		}
+	}
+	for action, key := range cfg.Keys {
+		if err := app.SetKey(action, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "show or change defaults for flags, colors and key bindings",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "show config",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := json.MarshalIndent(cfg, "", " ")
+		fatal(err)
+		path, err := fs.ConfigFilePath(ConfigFile)
+		fatal(err)
+		fmt.Println("#", path)
+		fmt.Println(string(data))
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "set value in config, empty value removes it",
+	Long: `Set value in config. Empty value removes it. KEY could be:
+	FLAG - persistent flag, like zen or min-speed
+	COMMAND.FLAG - flag of command, like words.number or race-host.players
+	colors.ELEMENT - color of ` + strings.Join(view.ColorNames(), ", ") + `, like green or #00ff00
+	keys.ACTION - key for pause or quit, like Ctrl-P or Esc`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		fatal(setConfig(args[0], args[1]))
+		fatal(fs.SaveConfigJSON(ConfigFile, cfg))
+	},
+}
+
+// setConfig validates and sets value in config
+func setConfig(key, value string) error {
+	parts := strings.SplitN(key, ".", 2)
+	var values *map[string]string
+	var check func() error
+	switch {
+	case len(parts) == 1:
+		f := rootCmd.PersistentFlags().Lookup(key)
+		if f == nil {
+			return fmt.Errorf("Unknown flag %s", key)
+		}
+		if key == "data-dir" {
+			return fmt.Errorf("Flag data-dir could not be set in config, as config is loaded from it")
+		}
+		if key == "profile" && os.Getenv(fs.ProfileEnv) != "" {
+			return fmt.Errorf("Flag profile would not be used from config, as it is set by %s environment variable", fs.ProfileEnv)
+		}
+		values = &cfg.Flags
+		check = func() error { return f.Value.Set(value) }
+	case parts[0] == "colors":
+		values = &cfg.Colors
+		check = func() error { return view.SetColor(parts[1], value) }
+	case parts[0] == "keys":
+		values = &cfg.Keys
+		check = func() error { return app.SetKey(parts[1], value) }
+	default:
+		f, err := commandFlag(parts[0], parts[1])
+		if err != nil {
+			return err
+		}
+		if cfg.Commands == nil {
+			cfg.Commands = make(map[string]map[string]string)
+		}
+		cmdValues := cfg.Commands[parts[0]]
+		values = &cmdValues
+		check = func() error { return f.Value.Set(value) }
+		defer func() {
+			cfg.Commands[parts[0]] = cmdValues
+			if len(cmdValues) == 0 {
+				delete(cfg.Commands, parts[0])
+			}
+		}()
+	}
+	key = parts[len(parts)-1]
+	if value == "" {
+		delete(*values, key)
+		return nil
+	}
+	if err := check(); err != nil {
+		return err
+	}
+	if *values == nil {
+		*values = make(map[string]string)
+	}
+	(*values)[key] = value
+	return nil
+}
+
+// commandFlag finds flag of command by its key in config
+func commandFlag(command, name string) (*pflag.Flag, error) {
+	var find func(c *cobra.Command) *cobra.Command
+	find = func(c *cobra.Command) *cobra.Command {
+		if commandKey(c) == command {
+			return c
+		}
+		for _, sub := range c.Commands() {
+			if found := find(sub); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	c := find(rootCmd)
+	if c == nil || command == "" {
+		return nil, fmt.Errorf("Unknown command %s", command)
+	}
+	f := c.LocalNonPersistentFlags().Lookup(name)
+	if f == nil {
+		return nil, fmt.Errorf("Command %s has no flag %s", command, name)
+	}
+	return f, nil
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd, configSetCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bunyk/gokeybr/stats"
+	"github.com/spf13/cobra"
+)
+
+var exportFormat string
+var exportFrom string
+var exportTo string
+var exportOutput string
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export sessions or stats of n-grams as CSV or JSON",
+	Long: `Export sessions or stats of n-grams as CSV or JSON, for analysis in spreadsheets or notebooks.
+Durations are in seconds, accuracy and error rate are from 0 to 1.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var exportSessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "export start, duration, characters, WPM, accuracy, source and mode of each session",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to, err := exportRange()
+		fatal(err)
+		sessions, err := stats.ExportSessions(from, to)
+		fatal(err)
+		header := []string{"start", "duration", "chars", "wpm", "accuracy", "source", "mode"}
+		records := make([][]string, len(sessions))
+		for i, s := range sessions {
+			records[i] = []string{
+				s.Start.Format(time.RFC3339), formatFloat(s.Duration), strconv.Itoa(s.Chars),
+				formatFloat(s.WPM), formatFloat(s.Accuracy), s.Source, s.Mode,
+			}
+		}
+		fatal(export(sessions, header, records))
+	},
+}
+
+var exportNGramsCmd = &cobra.Command{
+	Use:   "ngrams",
+	Short: "export stats of each n-gram",
+	Long: `Export how many times each n-gram was typed, with how many errors, and how fast.
+Without --from and --to saved stats are exported, otherwise they are computed
+from sessions in that range.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to, err := exportRange()
+		fatal(err)
+		ngrams, err := stats.ExportNGrams(from, to)
+		fatal(err)
+		header := []string{"ngram", "n", "count", "typed", "errors", "error_rate", "duration", "wpm"}
+		records := make([][]string, len(ngrams))
+		for i, n := range ngrams {
+			records[i] = []string{
+				n.NGram, strconv.Itoa(n.Order), strconv.Itoa(n.Count), strconv.Itoa(n.Typed),
+				strconv.Itoa(n.Errors), formatFloat(n.ErrorRate), formatFloat(n.Duration), formatFloat(n.WPM),
+			}
+		}
+		fatal(export(ngrams, header, records))
+	},
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// exportRange parses --from and --to flags. Zero time is returned for flag that is not set.
+func exportRange() (from, to time.Time, err error) {
+	if from, _, err = parseDate(exportFrom); err != nil {
+		return
+	}
+	var dateOnly bool
+	if to, dateOnly, err = parseDate(exportTo); err != nil {
+		return
+	}
+	if dateOnly { // whole day is included
+		to = to.AddDate(0, 0, 1)
+	}
+	return
+}
+
+// parseDate parses date like 2006-01-02 in local time zone, or time in RFC3339 format
+func parseDate(s string) (t time.Time, dateOnly bool, err error) {
+	if s == "" {
+		return
+	}
+	if t, err = time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, true, nil
+	}
+	if t, err = time.Parse(time.RFC3339, s); err != nil {
+		err = fmt.Errorf("Could not parse %#v, should be date like 2006-01-02 or time like 2006-01-02T15:04:05Z", s)
+	}
+	return
+}
+
+// export writes data as JSON, or header with records as CSV, to output given by flags
+func export(data interface{}, header []string, records [][]string) error {
+	if exportFormat != "json" && exportFormat != "csv" { // checked before output file is truncated
+		return fmt.Errorf("Unknown format %#v, should be \"csv\" or \"json\"", exportFormat)
+	}
+	var w io.Writer = os.Stdout
+	if exportOutput != "" && exportOutput != "-" {
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch exportFormat {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(records); err != nil {
+			return err
+		}
+		return cw.Error()
+	}
+	return nil
+}
+
+func init() {
+	pf := exportCmd.PersistentFlags()
+	pf.StringVarP(&exportFormat, "format", "f", "csv", "Format of export: csv or json")
+	pf.StringVar(&exportFrom, "from", "", "Export only sessions started from this date, like 2006-01-02")
+	pf.StringVar(&exportTo, "to", "", "Export only sessions started till the end of this date")
+	pf.StringVarP(&exportOutput, "output", "o", "", "File to write export to (default - standard output)")
+	exportCmd.AddCommand(exportSessionsCmd)
+	exportCmd.AddCommand(exportNGramsCmd)
+	rootCmd.AddCommand(exportCmd)
+}
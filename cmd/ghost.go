@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bunyk/gokeybr/stats"
+	"github.com/spf13/cobra"
+)
+
+// ghostFlags are settings of ghost to race against, each command has its own
+type ghostFlags struct {
+	name string
+	wpm  float64
+}
+
+var textGhost, wordsGhost ghostFlags
+
+func (g *ghostFlags) add(c *cobra.Command) {
+	c.Flags().StringVarP(&g.name, "ghost", "g", "",
+		fmt.Sprintf("Race against %#v or %#v of previous sessions with the same text", stats.GhostBest, stats.GhostAverage),
+	)
+	c.Flags().Float64Var(&g.wpm, "ghost-wpm", 0,
+		"Race against ghost typing with given speed",
+	)
+}
+
+// load returns timeline or speed of ghost to race against, if it was requested by flags.
+// Should be called before screen is initialized, as it could print messages.
+func (g ghostFlags) load(text string) (timeline []float64, wpm float64) {
+	if g.wpm > 0 {
+		return nil, g.wpm
+	}
+	if g.name == "" {
+		return nil, 0
+	}
+	timeline, err := stats.GhostTimeline([]rune(text), g.name)
+	fatal(err)
+	if timeline == nil {
+		wpm = stats.AverageWPM()
+		fmt.Printf("This text was never typed till the end, racing against ghost with average speed %.1f wpm\n", wpm)
+	}
+	return timeline, wpm
+}
@@ -19,16 +19,48 @@ Examples:
 
 Key bindings:
 
-   ESC   quit
+   ESC     quit
+   Ctrl+P  pause, any key to continue (time in pause is not counted)
 
 Files:
-	gokeybr stores log of your training sessions in file ~/.gokeybr/sessions_log.jsonl.
+	gokeybr keeps data files in $XDG_DATA_HOME/gokeybr (~/.local/share/gokeybr by default),
+	config in $XDG_CONFIG_HOME/gokeybr (~/.config/gokeybr), and progress in files in
+	$XDG_STATE_HOME/gokeybr (~/.local/state/gokeybr). Files from ~/.gokeybr, where they
+	were kept before, are moved there. With --data-dir DIR all files are kept in DIR.
+
+	gokeybr stores log of your training sessions in file sessions_log.jsonl.
 	Each line in that file contains timestamp, text, and timeline of one session.
 	Timeline is list of values of seconds each character in text was typed.
 	Last value in timeline will give session duration.
+	Mistakes is list of wrong keystrokes, with position in text, expected and typed
+	characters, and time in seconds when they happened.
+	Pauses between key presses longer than --idle-limit are cut from timeline,
+	their original durations are saved in gaps. Training is true for sessions generated
+	from stats, their n-grams are not counted in frequencies.
 
 	Purpose of this file is to be able to compute more detailed stats later.
+	Version is version of schema of line, older lines are upgraded when loaded.
+	Sessions imported by "gokeybr import" also have source, and when their text
+	is not known, number of characters, duration in seconds and number of errors.
+	"gokeybr stats rebuild" computes stats.json from it again.
+
+	When started with --record-events, gokeybr also saves every key press to
+	events_log.jsonl. Each line contains start of session (same as in
+	sessions_log.jsonl), and list of events with key name, typed character,
+	modifiers, seconds since start of session and input position after key press.
+	Time is measured as it really passed, and session_time as in timeline,
+	without pauses and pauses cut by --idle-limit.
 
 	
-	~/.gokeybr/stats.json is used to store general statistics used to generate training sessions.
+	stats.json is used to store general statistics used to generate training sessions.
+
+	With --store sqlite, sessions, key events, stats and progress are kept in
+	gokeybr.db SQLite database in data directory instead. "gokeybr store import"
+	copies them there from JSON files.
+
+	config.json keeps defaults for flags, colors and key bindings,
+	set by "gokeybr config set".
+
+	When --profile NAME is given (or GOKEYBR_PROFILE environment variable is set),
+	all files except config are kept in profiles/NAME/ subdirectories instead.
 `
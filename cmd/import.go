@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bunyk/gokeybr/stats"
+	"github.com/spf13/cobra"
+)
+
+var importFrom string
+
+var importCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "import history of typing tests from other programs",
+	Long: `Import history of typing tests, exported by other programs as CSV or JSON, so stats start from real data.
+Supported programs: ` + strings.Join(stats.ImportSources(), ", ") + `.
+
+Monkeytype and keybr.com do not export typed text, so their results are counted
+only in totals, like number of sessions and average speed. Results with text,
+like in typeracer exports, also update counts of n-grams, but not their speed,
+as only speed of whole text is known. Results imported before are skipped.
+Use "-" as FILE to read from standard input.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			fatal(err)
+			defer f.Close()
+			r = f
+		}
+		imported, skipped, err := stats.Import(importFrom, r)
+		fatal(err)
+		fmt.Printf("Imported %d results from %s\n", imported, importFrom)
+		if skipped > 0 {
+			fmt.Printf("Skipped %d results without time, speed or length\n", skipped)
+		}
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFrom, "from", "", "Program that exported file: "+strings.Join(stats.ImportSources(), ", "))
+	_ = importCmd.MarkFlagRequired("from")
+	rootCmd.AddCommand(importCmd)
+}
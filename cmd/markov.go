@@ -3,12 +3,12 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/bunyk/gokeybr/app"
 	"github.com/bunyk/gokeybr/stats"
 	"github.com/spf13/cobra"
 )
 
 var markovLength int
+var markovOrder int
 
 var markovCmd = &cobra.Command{
 	Use:     "random [flags]",
@@ -19,13 +19,13 @@ var markovCmd = &cobra.Command{
 		if markovLength < stats.MinSessionLength {
 			fmt.Printf("Sequence should be at least %d characters long\n", stats.MinSessionLength)
 		}
-		text, err := stats.RandomTraining(markovLength)
+		more, err := stats.RandomTrainingStream(markovLength, markovOrder)
 		fatal(err)
-		a, err := app.New(text)
+		a, err := newApp(more())
 		fatal(err)
-		a.Zen = zen
-		a.Mute = mute
-		a.MinSpeed = minSpeed
+		if duration > 0 { // make sure text will not end before time is up
+			a.More = more
+		}
 
 		err = a.Run()
 		fatal(err)
@@ -38,5 +38,8 @@ func init() {
 	markovCmd.Flags().IntVarP(&markovLength, "length", "l", 100,
 		"Minimal lenght in characters of generated text (default 100)",
 	)
+	markovCmd.Flags().IntVarP(&markovOrder, "order", "o", stats.DefaultOrder,
+		"Length of character sequences to train: 1 - keys, 2 - bigrams, 3 - trigrams",
+	)
 	rootCmd.AddCommand(markovCmd)
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bunyk/gokeybr/fs"
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "manage profiles, each with separate stats and history",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list profiles, current one is marked with *",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles, err := fs.ListProfiles()
+		fatal(err)
+		current := fs.Profile
+		if current == "" {
+			current = fs.DefaultProfile
+		}
+		for _, p := range profiles {
+			mark := " "
+			if p == current {
+				mark = "*"
+			}
+			fmt.Println(mark, p)
+		}
+	},
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "create empty profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fatal(fs.CreateProfile(args[0]))
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "delete profile with all its stats",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fatal(fs.DeleteProfile(args[0]))
+	},
+}
+
+var profileCopyCmd = &cobra.Command{
+	Use:   "copy FROM TO",
+	Short: "create new profile with copy of stats of existing one",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		fatal(fs.CopyProfile(args[0], args[1]))
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd, profileCreateCmd, profileDeleteCmd, profileCopyCmd)
+	rootCmd.AddCommand(profileCmd)
+}
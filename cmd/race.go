@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/bunyk/gokeybr/app"
+	"github.com/bunyk/gokeybr/phrase"
+	"github.com/bunyk/gokeybr/race"
+	"github.com/spf13/cobra"
+)
+
+var racerName string
+var racePlayers int
+var raceListen string
+var raceCountdown time.Duration
+var raceWords int
+var raceLength int
+var raceDictionary string
+
+var raceCmd = &cobra.Command{
+	Use:   "race",
+	Short: "compete in typing with others in local network",
+}
+
+var raceHostCmd = &cobra.Command{
+	Use:   "host [flags] [file with text (\"-\" - stdin)]",
+	Short: "start race and wait for others to join",
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if racePlayers < 2 {
+			fmt.Println("Need at least two players to race")
+			return
+		}
+		var text string
+		var err error
+		if len(args) > 0 {
+			text, _, err = phrase.FromFile(args[0], 0, raceLength)
+		} else {
+			text, err = phrase.Words(hostDictionary(cmd), raceWords)
+		}
+		fatal(err)
+
+		server, err := race.Listen(raceListen, text, racePlayers, raceCountdown)
+		fatal(err)
+		_, port, _ := net.SplitHostPort(server.Addr().String())
+		fmt.Printf("Waiting for %d more players. They could join with:\n", racePlayers-1)
+		for _, ip := range localIPs() {
+			fmt.Printf("    gokeybr race join %s\n", net.JoinHostPort(ip, port))
+		}
+		served := make(chan error)
+		go func() {
+			served <- server.Serve()
+		}()
+
+		client, err := race.Join(net.JoinHostPort("127.0.0.1", port), racerName)
+		fatal(err)
+		runRace(client)
+		fmt.Println("Waiting for other players to leave the race...")
+		fatal(<-served)
+	},
+}
+
+var raceJoinCmd = &cobra.Command{
+	Use:   "join [flags] address of host",
+	Short: "join race started by someone else",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := race.Join(args[0], racerName)
+		fatal(err)
+		fmt.Println("Joined, waiting for other players...")
+		runRace(client)
+	},
+}
+
+func runRace(client *race.Client) {
+	defer client.Close()
+	text, start, err := client.WaitStart()
+	fatal(err)
+
+	kb, heat, err := loadKeyboard()
+	fatal(err)
+	a, err := app.New(text)
+	fatal(err)
+	a.Zen = zen
+	a.Mute = mute
+	a.RecordEvents = recordEvents
+	a.Keyboard = kb
+	a.KeyHeat = heat
+	a.Remap = remap
+	a.Race = client
+	a.RaceStart = start
+	a.RunRace()
+
+	saveStats(a, false)
+	fmt.Println("Race results:")
+	for i, r := range race.Standings(client.Racers()) {
+		status := fmt.Sprintf("%.0f%%", float64(r.Position)/float64(len(a.Text))*100)
+		if r.Finished {
+			status = fmt.Sprintf("finished in %.1f seconds", r.Time)
+		} else if r.Left {
+			status += ", left"
+		}
+		fmt.Printf("%3d. %s - %s\n", i+1, r.Name, status)
+	}
+}
+
+// localIPs returns IPv4 addresses of this machine in local network
+// hostDictionary returns file to load words of race from. Dictionary configured
+// for words command is used, unless it is given for race in flags or config.
+func hostDictionary(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("dictionary") || cfg.Commands["race-host"]["dictionary"] != "" {
+		return raceDictionary
+	}
+	if d := cfg.Commands["words"]["dictionary"]; d != "" {
+		return d
+	}
+	return raceDictionary
+}
+
+func localIPs() []string {
+	var res []string
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+			res = append(res, ipnet.IP.String())
+		}
+	}
+	if len(res) == 0 {
+		res = append(res, "127.0.0.1")
+	}
+	return res
+}
+
+func init() {
+	raceCmd.PersistentFlags().StringVar(&racerName, "name", os.Getenv("USER"),
+		"Name to show to other players",
+	)
+	raceHostCmd.Flags().IntVarP(&racePlayers, "players", "p", 2,
+		"Number of players including you, race starts when all joined",
+	)
+	raceHostCmd.Flags().StringVarP(&raceListen, "listen", "a", ":"+race.DefaultPort,
+		"Address to listen for other players on",
+	)
+	raceHostCmd.Flags().DurationVar(&raceCountdown, "countdown", 5*time.Second,
+		"Time to get ready after all players joined",
+	)
+	raceHostCmd.Flags().IntVarP(&raceWords, "number", "n", 10,
+		"Number of words to type, when file is not given",
+	)
+	raceHostCmd.Flags().IntVarP(&raceLength, "length", "l", 0,
+		"Minimal lenght in characters of text from file (default 0 - unlimited)",
+	)
+	raceHostCmd.Flags().StringVar(&raceDictionary, "dictionary", defaultDictionary,
+		"File to load words from, when file is not given, if not set uses dictionary of words command from config",
+	)
+	raceCmd.AddCommand(raceHostCmd)
+	raceCmd.AddCommand(raceJoinCmd)
+	rootCmd.AddCommand(raceCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bunyk/gokeybr/app"
+	"github.com/bunyk/gokeybr/stats"
+	"github.com/spf13/cobra"
+)
+
+var replayLast bool
+var replaySpeed float64
+
+var replayCmd = &cobra.Command{
+	Use:   "replay [flags] [number of session in log]",
+	Short: "show how one of the past sessions was typed",
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		n := 0 // last session
+		if len(args) > 0 && !replayLast {
+			var err error
+			n, err = strconv.Atoi(args[0])
+			fatal(err)
+			if n < 1 {
+				fmt.Println("Sessions are numbered starting from 1")
+				return
+			}
+		}
+		if replaySpeed <= 0 {
+			fmt.Println("Speed should be positive")
+			return
+		}
+		session, err := stats.LoadSession(n)
+		fatal(err)
+
+		a, err := app.New(string(session.Text))
+		fatal(err)
+		a.Zen = zen
+		a.Replay(session, replaySpeed)
+
+		fmt.Printf("Session started at %s\n", session.Start.Local().Format("2006-01-02 15:04:05"))
+		if len(session.Events) == 0 {
+			fmt.Println("Raw events were not recorded, replay was reconstructed from timeline")
+		}
+		fmt.Println(a.Summary())
+	},
+}
+
+func init() {
+	replayCmd.Flags().BoolVar(&replayLast, "last", false,
+		"Replay last session (default when number is not given)",
+	)
+	replayCmd.Flags().Float64VarP(&replaySpeed, "speed", "x", 1.0,
+		"How many times faster than real session to replay, like 2 or 0.5",
+	)
+	rootCmd.AddCommand(replayCmd)
+}
@@ -3,32 +3,168 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/bunyk/gokeybr/app"
+	"github.com/bunyk/gokeybr/fs"
+	"github.com/bunyk/gokeybr/layout"
+	"github.com/bunyk/gokeybr/phrase"
 	"github.com/bunyk/gokeybr/stats"
 )
 
 var zen bool
 var mute bool
 var minSpeed int
+var duration time.Duration
+var recordEvents bool
+var idleLimit time.Duration
+var layoutName string
+var keyboard string
+var simulate string
+var remap map[rune]rune // from layout set in system to simulated
+var storeName string
 var rootCmd = &cobra.Command{
 	Use:  "gokeybr",
 	Long: Help,
 	Run: func(cmd *cobra.Command, args []string) {
 		_ = cmd.Help()
 	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		moved, err := fs.MigrateLegacy([]string{ConfigFile}, []string{stats.ProgressFile})
+		if err != nil {
+			fmt.Printf("Warning: could not move files from ~/%s to XDG base directories: %s\n", fs.LegacyDir, err)
+			fs.UseLegacyDir()
+		} else if moved {
+			fmt.Printf("Moved files from ~/%s to XDG base directories\n", fs.LegacyDir)
+		}
+		if err := loadConfig(); err != nil {
+			fmt.Println("Could not load config:", err)
+		}
+		if cmd.Parent() != configCmd { // broken config could be fixed
+			if err := applyConfig(cmd); err != nil {
+				return err
+			}
+		}
+		if fs.Profile != "" && cmd.Parent() != profileCmd { // profiles are managed even if current one does not exist
+			if err := fs.CheckProfile(fs.Profile); err != nil {
+				return err
+			}
+		}
+		if err := setupSimulation(); err != nil {
+			return err
+		}
+		return setupStore()
+	},
+}
+
+// Names of stores for --store flag
+const (
+	jsonStore   = "json"
+	sqliteStore = "sqlite"
+)
+
+// setupStore opens store where sessions and stats are kept
+func setupStore() error {
+	var st stats.Store
+	switch storeName {
+	case jsonStore:
+		st = stats.NewJSONStore()
+	case sqliteStore:
+		var err error
+		if st, err = stats.OpenSQLite(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown store %#v, should be %#v or %#v", storeName, jsonStore, sqliteStore)
+	}
+	stats.SetStore(st)
+	phrase.SetStore(st) // progress in text files is kept with sessions
+	return nil
+}
+
+// setupSimulation prepares remapping of keys when layout is simulated
+func setupSimulation() error {
+	if simulate == "" {
+		return nil
+	}
+	native, err := layout.Get(layoutName)
+	if err != nil {
+		return err
+	}
+	target, err := layout.Get(simulate)
+	if err != nil {
+		return err
+	}
+	remap = layout.Remap(native, target)
+	stats.Layout = target.Name
+	return nil
+}
+
+// practicedLayout returns name of layout which is typed
+func practicedLayout() string {
+	if simulate != "" {
+		return simulate
+	}
+	return layoutName
+}
+
+// newApp creates app to type text, configured by persistent flags
+func newApp(text string) (*app.App, error) {
+	kb, heat, err := loadKeyboard()
+	if err != nil {
+		return nil, err
+	}
+	a, err := app.New(text)
+	if err != nil {
+		return a, err
+	}
+	a.Keyboard = kb
+	a.KeyHeat = heat
+	a.Remap = remap
+	a.Zen = zen
+	a.Mute = mute
+	a.MinSpeed = minSpeed
+	a.Duration = duration
+	a.RecordEvents = recordEvents
+	a.IdleLimit = idleLimit
+	return a, nil
+}
+
+// loadKeyboard returns layout of keyboard to show, if requested by flags, and how bad each of its keys is typed.
+// Should be called before screen is initialized, as stats could print warnings.
+func loadKeyboard() (*layout.Layout, map[layout.Position]float64, error) {
+	if keyboard == "" {
+		return nil, nil, nil
+	}
+	if keyboard != "speed" && keyboard != "errors" {
+		return nil, nil, fmt.Errorf("Keyboard could be colored by speed or errors, got %s", keyboard)
+	}
+	kb, err := layout.Get(practicedLayout())
+	if err != nil {
+		return nil, nil, err
+	}
+	heat, err := stats.KeyHeat(kb, keyboard == "errors")
+	return kb, heat, err
 }
 
 func saveStats(a *app.App, isTraining bool) {
 	fmt.Println(a.Summary())
-	if err := stats.SaveSession(
-		a.StartedAt,
-		a.Text[:a.InputPosition],
-		a.Timeline[:a.InputPosition],
-		isTraining,
-	); err != nil {
+	if gs := a.GhostSummary(); gs != "" {
+		fmt.Println(gs)
+	}
+	if err := stats.SaveSession(stats.Session{
+		Start:    a.StartedAt,
+		Text:     a.Text[:a.InputPosition],
+		Timeline: a.Timeline[:a.InputPosition],
+		Mistakes: a.Mistakes,
+		Mode:     a.Pass.String(),
+		Training: isTraining,
+		Events:   a.Events,
+		Gaps:     a.Gaps,
+	}); err != nil {
 		fmt.Println(err)
 	}
 }
@@ -45,5 +181,16 @@ func Execute() {
 	pf.BoolVarP(&zen, "zen", "z", false, "run training session in \"zen mode\" (minimal screen output)")
 	pf.BoolVarP(&mute, "mute", "m", false, "Do not produce sound when wrong key is hit")
 	pf.IntVarP(&minSpeed, "min-speed", "s", 0, "Minimal speed limit in WPM")
+	pf.BoolVar(&recordEvents, "record-events", false, "Save every key press to "+stats.EventsLogFile+" for later analysis")
+	pf.DurationVar(&idleLimit, "idle-limit", 0, "Pauses between key presses longer than that are cut to it, like 5s (default 0 - do not cut)")
+	pf.IntSliceVar(&stats.Orders, "ngrams", stats.Orders, "Orders of n-grams to collect stats for (1 - keys, 2 - bigrams, 3 - trigrams...)")
+	pf.StringVar(&layoutName, "layout", "qwerty", "Keyboard layout set in system: "+strings.Join(layout.Builtin(), ", ")+", or file with layout")
+	pf.StringVar(&fs.DataDir, "data-dir", "", "Keep all files in this directory, instead of XDG base directories")
+	pf.StringVar(&fs.Profile, "profile", os.Getenv(fs.ProfileEnv), "Profile to keep stats in, instead of default (also set by "+fs.ProfileEnv+" environment variable)")
+	pf.StringVar(&simulate, "simulate", "", "Practice given layout, while keyboard is set to --layout. Stats are kept separately")
+	pf.StringVar(&keyboard, "keyboard", "", "Show keyboard under text, colored by speed or errors of typing each key")
+	pf.Lookup("keyboard").NoOptDefVal = "speed"
+	pf.StringVar(&storeName, "store", jsonStore, "Where to keep sessions and stats: "+jsonStore+" files or "+sqliteStore+" database")
+	pf.DurationVarP(&duration, "duration", "t", 0, "End session after given time, like 60s (default 0 - unlimited)")
 	fatal(rootCmd.Execute())
 }
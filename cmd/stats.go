@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/bunyk/gokeybr/layout"
 	"github.com/bunyk/gokeybr/stats"
 	"github.com/spf13/cobra"
 )
@@ -18,9 +19,36 @@ var statsCmd = &cobra.Command{
 			return
 		}
 		fmt.Println(text)
+
+		l, err := layout.Get(practicedLayout())
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		text, err = stats.LayoutReport(l)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(text)
+	},
+}
+
+var statsRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "compute stats again from log of all sessions",
+	Long: `Compute stats again from log of all sessions, replacing saved stats.
+Useful to recover from corrupted stats file, or to collect stats of n-grams given by --ngrams.
+Sessions saved before training sessions were marked in log are counted as regular.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sessions, err := stats.Rebuild()
+		fatal(err)
+		fmt.Printf("Stats rebuilt from %d sessions\n", sessions)
 	},
 }
 
 func init() {
+	statsCmd.AddCommand(statsRebuildCmd)
 	rootCmd.AddCommand(statsCmd)
 }
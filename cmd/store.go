@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bunyk/gokeybr/stats"
+	"github.com/spf13/cobra"
+)
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "manage storage of sessions and stats",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var storeImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "copy sessions and stats from JSON files to SQLite database",
+	Long: `Copy log of sessions, recorded key events and stats from JSON files to SQLite database,
+to use it with --store sqlite. JSON files are left as they are.
+Database should not have any sessions yet.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := stats.OpenSQLite()
+		fatal(err)
+		defer db.Close()
+		sessions, err := stats.ImportJSON(db)
+		fatal(err)
+		fmt.Printf("Imported %d sessions\n", sessions)
+	},
+}
+
+func init() {
+	storeCmd.AddCommand(storeImportCmd)
+	rootCmd.AddCommand(storeCmd)
+}
@@ -1,26 +1,33 @@
 package cmd
 
 import (
-	"github.com/bunyk/gokeybr/app"
+	"fmt"
+
 	"github.com/bunyk/gokeybr/phrase"
+	"github.com/bunyk/gokeybr/stats"
 	"github.com/spf13/cobra"
 )
 
 var offset, limit int
+var drill bool
 var textCmd = &cobra.Command{
 	Use:     "text [flags] [file with text (\"-\" - stdin)]",
 	Aliases: []string{"file"},
 	Short:   "train to type contents of some file",
 	Args:    cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if drill {
+			runDrill(args[0])
+			return
+		}
 		text, skipped, err := phrase.FromFile(args[0], offset, limit)
 		fatal(err)
+		ghostTimeline, ghostWPM := textGhost.load(text)
 
-		a, err := app.New(text)
+		a, err := newApp(text)
 		fatal(err)
-		a.Zen = zen
-		a.Mute = mute
-		a.MinSpeed = minSpeed
+		a.GhostTimeline = ghostTimeline
+		a.GhostWPM = ghostWPM
 		a.Offset = skipped
 
 		a.Run()
@@ -33,6 +40,27 @@ var textCmd = &cobra.Command{
 	},
 }
 
+func runDrill(filename string) {
+	if textGhost.name != "" {
+		fatal(fmt.Errorf("--ghost could not be used with --drill, as ghost typed whole text and not separate lines, use --ghost-wpm"))
+	}
+	lines, _, err := phrase.LinesFromFile(filename, offset, limit)
+	fatal(err)
+	targetWPM := stats.AverageWPM()
+
+	a, err := newApp("") // only settings are used, each pass is a new session
+	fatal(err)
+	a.GhostWPM = textGhost.wpm
+
+	sessions, linesTyped := a.RunDrill(lines, targetWPM)
+	for _, s := range sessions {
+		saveStats(s, false)
+	}
+
+	err = phrase.UpdateFileProgress(filename, linesTyped, offset)
+	fatal(err)
+}
+
 func init() {
 	textCmd.Flags().IntVarP(&limit, "length", "l", 0,
 		"Minimal lenght in characters of text to train on (default 0 - unlimited)",
@@ -40,5 +68,9 @@ func init() {
 	textCmd.Flags().IntVarP(&offset, "offset", "o", -1,
 		"Offset in lines when loading file (default 0)",
 	)
+	textCmd.Flags().BoolVarP(&drill, "drill", "d", false,
+		"Type each line three times: slow (without errors), fast (errors ignored) and normal",
+	)
+	textGhost.add(textCmd)
 	rootCmd.AddCommand(textCmd)
 }
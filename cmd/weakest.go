@@ -3,12 +3,12 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/bunyk/gokeybr/app"
 	"github.com/bunyk/gokeybr/stats"
 	"github.com/spf13/cobra"
 )
 
 var weakestLength int
+var weakestOrder int
 
 var weakestCmd = &cobra.Command{
 	Use:   "weakest [flags]",
@@ -18,13 +18,10 @@ var weakestCmd = &cobra.Command{
 		if weakestLength < stats.MinSessionLength {
 			fmt.Printf("Sequence should be at least %d characters long\n", stats.MinSessionLength)
 		}
-		text, err := stats.WeakestTraining(weakestLength)
+		text, err := stats.WeakestTraining(weakestLength, weakestOrder)
 		fatal(err)
-		a, err := app.New(text)
+		a, err := newApp(text)
 		fatal(err)
-		a.Zen = zen
-		a.Mute = mute
-		a.MinSpeed = minSpeed
 
 		err = a.Run()
 		fatal(err)
@@ -37,5 +34,8 @@ func init() {
 	weakestCmd.Flags().IntVarP(&weakestLength, "length", "l", 50,
 		"Minimal lenght in characters of generated text (default 50)",
 	)
+	weakestCmd.Flags().IntVarP(&weakestOrder, "order", "o", stats.DefaultOrder,
+		"Length of character sequences to train: 1 - keys, 2 - bigrams, 3 - trigrams",
+	)
 	rootCmd.AddCommand(weakestCmd)
 }
@@ -3,12 +3,14 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/bunyk/gokeybr/app"
 	"github.com/bunyk/gokeybr/phrase"
 	"github.com/spf13/cobra"
 )
 
 var wordsCount int
+var dictionary string
+
+const defaultDictionary = "/usr/share/dict/words"
 
 var wordsCmd = &cobra.Command{
 	Use:   "words [flags] [optional file to load words from (one word per line, \"-\" - stdin)]",
@@ -19,17 +21,23 @@ var wordsCmd = &cobra.Command{
 			fmt.Println("Need more then one word to start exercise")
 			return
 		}
-		filename := "/usr/share/dict/words"
+		filename := dictionary
 		if len(args) > 0 {
 			filename = args[0]
 		}
-		text, err := phrase.Words(filename, wordsCount)
+		words, err := phrase.NewWordSource(filename)
 		fatal(err)
-		a, err := app.New(text)
+		text := words.Next(wordsCount)
+		ghostTimeline, ghostWPM := wordsGhost.load(text)
+		a, err := newApp(text)
 		fatal(err)
-		a.Zen = zen
-		a.Mute = mute
-		a.MinSpeed = minSpeed
+		a.GhostTimeline = ghostTimeline
+		a.GhostWPM = ghostWPM
+		if duration > 0 { // make sure text will not end before time is up
+			a.More = func() string {
+				return " " + words.Next(wordsCount)
+			}
+		}
 
 		err = a.Run()
 		fatal(err)
@@ -41,5 +49,9 @@ func init() {
 	wordsCmd.Flags().IntVarP(&wordsCount, "number", "n", 10,
 		"Number of words to type (default 10)",
 	)
+	wordsCmd.Flags().StringVar(&dictionary, "dictionary", defaultDictionary,
+		"File to load words from, when not given as argument",
+	)
+	wordsGhost.add(wordsCmd)
 	rootCmd.AddCommand(wordsCmd)
 }
@@ -0,0 +1,213 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// DataDir, when set, is the only directory where all files are kept,
+// like in ~/.gokeybr before. Useful for experiments and tests.
+var DataDir string
+
+// Kind of file determines directory where it is kept, according to XDG base directory specification
+type Kind int
+
+const (
+	Data   Kind = iota // stats and logs of sessions
+	Config             // preferences, shared by all profiles
+	State              // progress in files, which is not as valuable as data
+)
+
+var xdgDirs = map[Kind]struct {
+	env      string // environment variable with base directory
+	fallback string // base directory relative to home, when variable is not set
+}{
+	Data:   {"XDG_DATA_HOME", ".local/share"},
+	Config: {"XDG_CONFIG_HOME", ".config"},
+	State:  {"XDG_STATE_HOME", ".local/state"},
+}
+
+const appDir = "gokeybr"
+
+// LegacyDir is where all files were kept before, relative to home
+const LegacyDir = ".gokeybr"
+
+// Dir returns directory where files of given kind are kept
+func Dir(kind Kind) (string, error) {
+	if DataDir != "" {
+		return DataDir, nil
+	}
+	xdg := xdgDirs[kind]
+	if base := os.Getenv(xdg.env); filepath.IsAbs(base) { // relative paths should be ignored
+		return filepath.Join(base, appDir), nil
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", fmt.Errorf("Neither HOME nor %s is set, do not know where to keep files. Use --data-dir", xdg.env)
+	}
+	return filepath.Join(home, xdg.fallback, appDir), nil
+}
+
+// profileDir returns directory with files of given kind for profile
+func profileDir(kind Kind, name string) (string, error) {
+	dir, err := Dir(kind)
+	if err != nil {
+		return "", err
+	}
+	if name == "" || name == DefaultProfile || kind == Config {
+		return dir, nil
+	}
+	return filepath.Join(dir, profilesDir, name), nil
+}
+
+// filePath returns path to file of given kind in current profile
+func filePath(kind Kind, name string) (string, error) {
+	dir, err := profileDir(kind, Profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// writablePath returns path to file, making sure its directory exists
+func writablePath(kind Kind, name string) (string, error) {
+	path, err := filePath(kind, name)
+	if err != nil {
+		return "", err
+	}
+	return path, os.MkdirAll(filepath.Dir(path), os.ModePerm)
+}
+
+// MigrateLegacy moves files from ~/.gokeybr to XDG directories, if they were not moved yet.
+// configFiles and stateFiles are names of files that are moved to config and state directories,
+// other files are moved to data directory. Returns true when files were moved.
+func MigrateLegacy(configFiles, stateFiles []string) (bool, error) {
+	home := os.Getenv("HOME")
+	if DataDir != "" || home == "" {
+		return false, nil
+	}
+	legacy := filepath.Join(home, LegacyDir)
+	if _, err := os.Stat(legacy); err != nil {
+		return false, nil // nothing to migrate
+	}
+	data, err := Dir(Data)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(data); err == nil {
+		return false, nil // already migrated, or started from scratch
+	}
+	if err := os.MkdirAll(filepath.Dir(data), os.ModePerm); err != nil {
+		return false, err
+	}
+	if err := move(legacy, data); err != nil {
+		return false, err
+	}
+	for _, name := range configFiles {
+		if err := moveFile(Config, data, "", name); err != nil {
+			return true, err
+		}
+	}
+	profiles, err := ListProfiles()
+	if err != nil {
+		return true, err
+	}
+	for _, profile := range profiles {
+		from, err := profileDir(Data, profile)
+		if err != nil {
+			return true, err
+		}
+		for _, name := range stateFiles {
+			if err := moveFile(State, from, profile, name); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// UseLegacyDir makes all files to be kept in ~/.gokeybr, like before, if it still exists.
+// Used when it could not be moved to XDG directories.
+func UseLegacyDir() {
+	home := os.Getenv("HOME")
+	if DataDir != "" || home == "" {
+		return
+	}
+	legacy := filepath.Join(home, LegacyDir)
+	if _, err := os.Stat(legacy); err == nil {
+		DataDir = legacy
+	}
+}
+
+// moveFile moves file from directory to directory of given kind of profile, if it exists
+func moveFile(kind Kind, from, profile, name string) error {
+	src := filepath.Join(from, name)
+	if _, err := os.Stat(src); err != nil {
+		return nil
+	}
+	dir, err := profileDir(kind, profile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	return move(src, filepath.Join(dir, name))
+}
+
+var rename = os.Rename // replaced in tests, to simulate other filesystem
+
+// move renames file or directory, or copies it and removes original
+// when it is moved to other filesystem
+func move(src, dst string) error {
+	err := rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	// copied next to destination first, so it does not look moved if copy fails
+	tmp := dst + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := copyTree(src, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies file, or directory with all its files
+func copyTree(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(dst, data, info.Mode().Perm())
+	}
+	if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	infos, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if err := copyTree(filepath.Join(src, info.Name()), filepath.Join(dst, info.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
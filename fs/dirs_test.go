@@ -0,0 +1,69 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestMigrateLegacy(t *testing.T) {
+	testMigrateLegacy(t)
+}
+
+func TestMigrateLegacyToOtherFilesystem(t *testing.T) {
+	rename = func(from, to string) error {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: syscall.EXDEV}
+	}
+	defer func() { rename = os.Rename }()
+	testMigrateLegacy(t)
+}
+
+func testMigrateLegacy(t *testing.T) {
+	home, err := ioutil.TempDir("", "gokeybr-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("HOME", home)
+	for _, xdg := range xdgDirs {
+		os.Unsetenv(xdg.env)
+	}
+	os.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+
+	legacy := filepath.Join(home, LegacyDir)
+	for _, name := range []string{"stats.json", "config.json", "progress.json", "profiles/work/progress.json"} {
+		path := filepath.Join(legacy, name)
+		_ = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		_ = ioutil.WriteFile(path, []byte("{}"), FileAccess)
+	}
+	moved, err := MigrateLegacy([]string{"config.json"}, []string{"progress.json"})
+	if err != nil || !moved {
+		t.Fatalf("Expected files to be moved, got %v, %v", moved, err)
+	}
+	for _, path := range []string{
+		".local/share/gokeybr/stats.json",
+		".config/gokeybr/config.json",
+		"state/gokeybr/progress.json",
+		"state/gokeybr/profiles/work/progress.json",
+	} {
+		if _, err := os.Stat(filepath.Join(home, path)); err != nil {
+			t.Errorf("Expected %s to exist after migration: %s", path, err)
+		}
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("Legacy directory should be moved")
+	}
+	if moved, _ := MigrateLegacy(nil, nil); moved {
+		t.Errorf("Files should be moved only once")
+	}
+
+	os.Unsetenv("HOME")
+	if _, err := Dir(Data); err == nil {
+		t.Errorf("Expected error when HOME is not set")
+	}
+	if dir, err := Dir(State); err != nil || dir != filepath.Join(home, "state", "gokeybr") {
+		t.Errorf("Expected XDG_STATE_HOME to be used without HOME, got %s, %v", dir, err)
+	}
+}
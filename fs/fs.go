@@ -2,6 +2,7 @@ package fs
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -11,32 +12,142 @@ import (
 
 const FileAccess = 0644
 
-func homeFilePath(name string) string {
-	return filepath.Join(
-		os.Getenv("HOME"),
-		".gokeybr",
-		name,
-	)
+func SaveJSON(filename string, o interface{}) error {
+	return save(Data, filename, o)
+}
+
+func LoadJSON(filename string, v interface{}) error {
+	return load(Data, filename, v)
+}
+
+// SaveStateJSON saves file to state directory of profile
+func SaveStateJSON(filename string, o interface{}) error {
+	return save(State, filename, o)
+}
+
+// LoadStateJSON loads file from state directory of profile
+func LoadStateJSON(filename string, v interface{}) error {
+	return load(State, filename, v)
+}
+
+// SaveConfigJSON saves file to config directory, shared by all profiles
+func SaveConfigJSON(filename string, o interface{}) error {
+	return save(Config, filename, o)
+}
+
+// LoadConfigJSON loads file from config directory, shared by all profiles
+func LoadConfigJSON(filename string, v interface{}) error {
+	return load(Config, filename, v)
+}
+
+// DataFilePath returns path to file in data directory of profile, creating directory if needed.
+// For files that are not JSON, like databases.
+func DataFilePath(filename string) (string, error) {
+	return writablePath(Data, filename)
+}
+
+// ConfigFilePath returns path to file in config directory
+func ConfigFilePath(filename string) (string, error) {
+	return filePath(Config, filename)
+}
+
+// UpdateJSON loads file into v, calls update and saves v back, holding lock on file,
+// so other processes could not change it meanwhile. When file does not exist, v is not changed.
+func UpdateJSON(filename string, v interface{}, update func() error) error {
+	return updateJSON(Data, filename, v, update)
+}
+
+// UpdateStateJSON is UpdateJSON for file in state directory of profile
+func UpdateStateJSON(filename string, v interface{}, update func() error) error {
+	return updateJSON(State, filename, v, update)
+}
+
+// ReplaceJSON saves value returned by build, holding lock on file while it is built.
+// Unlike UpdateJSON, file is not loaded, so it could be replaced even if broken.
+func ReplaceJSON(filename string, build func() (interface{}, error)) error {
+	return locked(Data, filename, func(path string) error {
+		o, err := build()
+		if err != nil {
+			return err
+		}
+		return saveJSON(path, o)
+	})
+}
+
+func updateJSON(kind Kind, filename string, v interface{}, update func() error) error {
+	return locked(kind, filename, func(path string) error {
+		if err := loadJSON(path, v); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := update(); err != nil {
+			return err
+		}
+		return saveJSON(path, v)
+	})
+}
+
+func save(kind Kind, filename string, o interface{}) error {
+	return locked(kind, filename, func(path string) error {
+		return saveJSON(path, o)
+	})
 }
-func mkdir() {
-	dir := homeFilePath("/")
-	if _, err := os.Stat(dir); err != nil {
-		_ = os.MkdirAll(dir, os.ModePerm)
+
+// locked calls f with path of file, holding lock on it
+func locked(kind Kind, filename string, f func(path string) error) error {
+	path, err := writablePath(kind, filename)
+	if err != nil {
+		return err
+	}
+	unlock, err := lock(path)
+	if err != nil {
+		return err
 	}
+	defer unlock()
+	return f(path)
 }
 
-func SaveJSON(filename string, o interface{}) error {
+func load(kind Kind, filename string, v interface{}) error {
+	path, err := filePath(kind, filename)
+	if err != nil {
+		return err
+	}
+	return loadJSON(path, v)
+}
+
+func saveJSON(path string, o interface{}) error {
 	data, err := json.MarshalIndent(o, "", " ")
 	if err != nil {
 		return err
 	}
-	mkdir()
-	return ioutil.WriteFile(homeFilePath(filename), data, FileAccess)
+	return writeAtomic(path, data)
 }
 
-func LoadJSON(filename string, v interface{}) error {
-	mkdir()
-	data, err := ioutil.ReadFile(homeFilePath(filename))
+// writeAtomic writes data to temporary file and renames it to path,
+// so file is never left half written when program is interrupted
+func writeAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), FileAccess) // temporary files are created private
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func loadJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
@@ -44,8 +155,16 @@ func LoadJSON(filename string, v interface{}) error {
 }
 
 func AppendJSONLine(filename string, v interface{}) error {
-	mkdir()
-	f, err := os.OpenFile(homeFilePath(filename), os.O_APPEND|os.O_CREATE|os.O_WRONLY, FileAccess)
+	path, err := writablePath(Data, filename)
+	if err != nil {
+		return err
+	}
+	unlock, err := lock(path) // so long lines from different processes are not mixed
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, FileAccess)
 	if err != nil {
 		return err
 	}
@@ -58,19 +177,72 @@ func AppendJSONLine(filename string, v interface{}) error {
 	return err
 }
 
+// RewriteJSONLines replaces lines of file by ones returned by update, holding lock on file.
+// update gets lines without line ends, nil when file does not exist yet.
+func RewriteJSONLines(filename string, update func(lines [][]byte) ([][]byte, error)) error {
+	return locked(Data, filename, func(path string) error {
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		var lines [][]byte
+		if len(data) > 0 {
+			lines = bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+		}
+		lines, err = update(lines)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		for _, line := range lines {
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		return writeAtomic(path, buf.Bytes())
+	})
+}
+
+// BackupFile copies data file to file with ".bak" suffix, and returns path of copy.
+// Returns empty path when file does not exist.
+func BackupFile(filename string) (string, error) {
+	path, err := filePath(Data, filename)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	backup := path + ".bak"
+	return backup, writeAtomic(backup, data)
+}
+
+// MaxJSONLineSize limits length of line JSONLinesIterator could read.
+// Sessions with recorded events could be much longer than default 64K.
+const MaxJSONLineSize = 16 * 1024 * 1024
+
 type JSONLinesIterator struct {
 	scanner *bufio.Scanner
 	file    *os.File
 }
 
 func NewJSONLinesIterator(filename string) (*JSONLinesIterator, error) {
-	file, err := os.Open(homeFilePath(filename))
+	path, err := filePath(Data, filename)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, MaxJSONLineSize)
 	return &JSONLinesIterator{
 		file:    file,
-		scanner: bufio.NewScanner(file),
+		scanner: scanner,
 	}, nil
 }
 
@@ -0,0 +1,48 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestUpdateJSONIsNotClobbered(t *testing.T) {
+	_, cleanup := withTempDataDir(t)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var counter int
+			if err := UpdateJSON("counter.json", &counter, func() error {
+				counter++
+				return nil
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var counter int
+	if err := LoadJSON("counter.json", &counter); err != nil || counter != 20 {
+		t.Errorf("Expected every update to be counted, got %d, %v", counter, err)
+	}
+}
+
+// withTempDataDir keeps files of test in temporary data directory.
+// Returned cleanup removes it and restores default.
+func withTempDataDir(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "gokeybr-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	DataDir = dir
+	return dir, func() {
+		DataDir = ""
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,7 @@
+package fs
+
+// lock is not implemented on Windows, writes are still atomic, but could be lost when
+// two processes update same file.
+func lock(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
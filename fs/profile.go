@@ -0,0 +1,172 @@
+package fs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultProfile keeps its files directly in data directory
+const DefaultProfile = "default"
+
+// ProfileEnv is environment variable to select profile when it is not given by flag
+const ProfileEnv = "GOKEYBR_PROFILE"
+
+// Profile which files are used, empty for default
+var Profile string
+
+const profilesDir = "profiles"
+
+// kinds of files that are kept separately for each profile
+var profileKinds = []Kind{Data, State}
+
+// CheckProfileName returns error if name could not be used for profile
+func CheckProfileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("Invalid profile name %#v", name)
+	}
+	return nil
+}
+
+// CheckProfile returns error if profile could not be used. Profiles are created only
+// explicitly, so that typo in name does not start new profile with empty stats.
+func CheckProfile(name string) error {
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+	if name != DefaultProfile && !profileExists(name) {
+		return fmt.Errorf("Profile %s does not exist, create it with \"gokeybr profile create %s\"", name, name)
+	}
+	return nil
+}
+
+func profileExists(name string) bool {
+	dir, err := profileDir(Data, name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// ListProfiles returns names of all profiles, starting from default
+func ListProfiles() ([]string, error) {
+	res := []string{DefaultProfile}
+	dir, err := Dir(Data)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(filepath.Join(dir, profilesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, nil
+		}
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.IsDir() && info.Name() != DefaultProfile {
+			res = append(res, info.Name())
+		}
+	}
+	return res, nil
+}
+
+func CreateProfile(name string) error {
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+	if profileExists(name) {
+		return fmt.Errorf("Profile %s already exists", name)
+	}
+	dir, err := profileDir(Data, name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, os.ModePerm)
+}
+
+func DeleteProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("Default profile could not be deleted")
+	}
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+	if !profileExists(name) {
+		return fmt.Errorf("Profile %s does not exist", name)
+	}
+	for _, kind := range profileKinds {
+		dir, err := profileDir(kind, name)
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyProfile creates profile to with copy of all files of profile from
+func CopyProfile(from, to string) error {
+	if err := CheckProfileName(from); err != nil {
+		return err
+	}
+	if !profileExists(from) {
+		return fmt.Errorf("Profile %s does not exist", from)
+	}
+	if err := CreateProfile(to); err != nil {
+		return err
+	}
+	for _, kind := range profileKinds {
+		if err := copyFiles(kind, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTemporary returns whether file is lock, or temporary file of save that is not finished
+func isTemporary(name string) bool {
+	return strings.HasSuffix(name, ".lock") || strings.Contains(name, ".tmp")
+}
+
+// copyFiles copies files of given kind between profiles
+func copyFiles(kind Kind, from, to string) error {
+	src, err := profileDir(kind, from)
+	if err != nil {
+		return err
+	}
+	dst, err := profileDir(kind, to)
+	if err != nil {
+		return err
+	}
+	infos, err := ioutil.ReadDir(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue // profiles of default profile are not copied
+		}
+		if isTemporary(info.Name()) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(src, info.Name()))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dst, info.Name()), data, FileAccess); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	home, cleanup := withTempDataDir(t)
+	defer cleanup()
+
+	if err := SaveJSON("stats.json", 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyProfile(DefaultProfile, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckProfile("wrok"); err == nil {
+		t.Errorf("Expected error for profile that was not created")
+	}
+	if err := CheckProfile("work"); err != nil {
+		t.Errorf("Expected copied profile to exist, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, profilesDir, "work", "stats.json.lock")); !os.IsNotExist(err) {
+		t.Errorf("Lock files should not be copied, got %v", err)
+	}
+	Profile = "work"
+	var v int
+	if err := LoadJSON("stats.json", &v); err != nil || v != 42 {
+		t.Errorf("Expected stats to be copied to new profile, got %v, %v", v, err)
+	}
+	_ = SaveJSON("stats.json", 43)
+	Profile = ""
+	if _ = LoadJSON("stats.json", &v); v != 42 {
+		t.Errorf("Stats of default profile should not be changed, got %v", v)
+	}
+
+	profiles, err := ListProfiles()
+	if err != nil || !reflect.DeepEqual(profiles, []string{DefaultProfile, "work"}) {
+		t.Errorf("Unexpected profiles %v, %v", profiles, err)
+	}
+	if err := CreateProfile("work"); err == nil {
+		t.Errorf("Expected error when creating existing profile")
+	}
+	if err := DeleteProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if profiles, _ := ListProfiles(); len(profiles) != 1 {
+		t.Errorf("Expected only default profile to be left, got %v", profiles)
+	}
+}
@@ -5,4 +5,6 @@ go 1.13
 require (
 	github.com/gdamore/tcell/v2 v2.0.0-dev
 	github.com/spf13/cobra v1.0.0
+	github.com/spf13/pflag v1.0.3
+	modernc.org/sqlite v1.23.1
 )
@@ -0,0 +1,254 @@
+// Package layout describes where keys are on keyboard, and which fingers type them
+package layout
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Hand that types a key
+type Hand int
+
+const (
+	Left Hand = iota
+	Right
+	Both // thumbs on space bar
+)
+
+// Finger that types a key
+type Finger int
+
+const (
+	LeftPinky Finger = iota
+	LeftRing
+	LeftMiddle
+	LeftIndex
+	Thumb
+	RightIndex
+	RightMiddle
+	RightRing
+	RightPinky
+)
+
+// Fingers lists all fingers from left to right
+var Fingers = []Finger{
+	LeftPinky, LeftRing, LeftMiddle, LeftIndex, Thumb, RightIndex, RightMiddle, RightRing, RightPinky,
+}
+
+var fingerNames = []string{
+	"Left pinky", "Left ring", "Left middle", "Left index", "Thumb",
+	"Right index", "Right middle", "Right ring", "Right pinky",
+}
+
+func (f Finger) String() string {
+	return fingerNames[f]
+}
+
+func (f Finger) Hand() Hand {
+	switch {
+	case f < Thumb:
+		return Left
+	case f > Thumb:
+		return Right
+	}
+	return Both
+}
+
+// Rows of keyboard, from top to bottom
+const (
+	NumberRow = iota
+	TopRow
+	HomeRow
+	BottomRow
+	SpaceRow
+)
+
+// Position of key on keyboard
+type Position struct {
+	Row    int
+	Column int // counted from left edge of row
+}
+
+// Key is position of rune on keyboard
+type Key struct {
+	Position
+	Finger Finger
+	Shift  bool // rune is typed with shift
+}
+
+// Layout maps runes to keys
+type Layout struct {
+	Name string
+	// Rows of runes typed without shift, and with shift, from number row to bottom row
+	Rows        []string
+	ShiftedRows []string
+
+	keys map[rune]Key
+}
+
+// New creates layout from rows of runes
+func New(name string, rows, shiftedRows []string) (*Layout, error) {
+	if len(rows) != SpaceRow || (len(shiftedRows) != 0 && len(shiftedRows) != SpaceRow) {
+		return nil, fmt.Errorf("Layout %s should have %d rows, and optionally %d shifted rows", name, SpaceRow, SpaceRow)
+	}
+	l := &Layout{
+		Name:        name,
+		Rows:        rows,
+		ShiftedRows: shiftedRows,
+		keys:        make(map[rune]Key),
+	}
+	l.keys[' '] = Key{Position: Position{Row: SpaceRow}, Finger: Thumb}
+	l.keys['\n'] = Key{Position: Position{HomeRow, len([]rune(rows[HomeRow]))}, Finger: RightPinky}
+	for shift, rs := range [][]string{shiftedRows, rows} { // so unshifted runes win, if repeated
+		for row, line := range rs {
+			for col, r := range []rune(line) {
+				l.keys[r] = Key{Position: Position{row, col}, Finger: fingerAt(row, col), Shift: shift == 0}
+			}
+		}
+	}
+	return l, nil
+}
+
+// fingerAt returns finger that is usually used to hit key in given row and column
+func fingerAt(row, col int) Finger {
+	if row == NumberRow { // number row is shifted left by half of key
+		col--
+	}
+	switch {
+	case col <= 0:
+		return LeftPinky
+	case col == 1:
+		return LeftRing
+	case col == 2:
+		return LeftMiddle
+	case col <= 4:
+		return LeftIndex
+	case col <= 6:
+		return RightIndex
+	case col == 7:
+		return RightMiddle
+	case col == 8:
+		return RightRing
+	}
+	return RightPinky
+}
+
+// Key returns position of rune on keyboard. ok is false when there is no such key in layout.
+func (l *Layout) Key(r rune) (k Key, ok bool) {
+	k, ok = l.keys[r]
+	return
+}
+
+// Rune returns rune typed by key at position p, with or without shift
+func (l *Layout) Rune(p Position, shift bool) (rune, bool) {
+	if p.Row == SpaceRow {
+		return ' ', true
+	}
+	rows := l.Rows
+	if shift {
+		rows = l.ShiftedRows
+	}
+	if p.Row >= len(rows) {
+		return 0, false
+	}
+	row := []rune(rows[p.Row])
+	if p.Column >= len(row) {
+		return 0, false
+	}
+	return row[p.Column], true
+}
+
+// Remap returns table to convert runes typed on keyboard with layout from,
+// to runes that would be typed by same keys with layout to.
+// Only runes that differ are in table.
+func Remap(from, to *Layout) map[rune]rune {
+	res := make(map[rune]rune)
+	for r, k := range from.keys {
+		if t, ok := to.Rune(k.Position, k.Shift); ok && t != r {
+			res[r] = t
+		}
+	}
+	return res
+}
+
+const (
+	numbers        = "`1234567890-="
+	shiftedNumbers = "~!@#$%^&*()_+"
+)
+
+var builtin = map[string][2][]string{
+	"qwerty": {
+		{numbers, "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"},
+		{shiftedNumbers, "QWERTYUIOP{}|", "ASDFGHJKL:\"", "ZXCVBNM<>?"},
+	},
+	"dvorak": {
+		{"`1234567890[]", "',.pyfgcrl/=\\", "aoeuidhtns-", ";qjkxbmwvz"},
+		{"~!@#$%^&*(){}", "\"<>PYFGCRL?+|", "AOEUIDHTNS_", ":QJKXBMWVZ"},
+	},
+	"colemak": {
+		{numbers, "qwfpgjluy;[]\\", "arstdhneio'", "zxcvbkm,./"},
+		{shiftedNumbers, "QWFPGJLUY:{}|", "ARSTDHNEIO\"", "ZXCVBKM<>?"},
+	},
+	"workman": {
+		{numbers, "qdrwbjfup;[]\\", "ashtgyneoi'", "zxmcvkl,./"},
+		{shiftedNumbers, "QDRWBJFUP:{}|", "ASHTGYNEOI\"", "ZXMCVKL<>?"},
+	},
+}
+
+// Builtin returns names of layouts that do not need to be loaded from file
+func Builtin() []string {
+	res := make([]string, 0, len(builtin))
+	for name := range builtin {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Get returns builtin layout with given name, or loads it from file
+func Get(name string) (*Layout, error) {
+	if rows, ok := builtin[strings.ToLower(name)]; ok {
+		return New(strings.ToLower(name), rows[0], rows[1])
+	}
+	if _, err := os.Stat(name); err != nil {
+		return nil, fmt.Errorf("Unknown layout %s, should be one of %s, or file with layout", name, strings.Join(Builtin(), ", "))
+	}
+	return Load(name)
+}
+
+// Load reads layout from file. File should contain 4 rows of runes typed without shift,
+// from number row to bottom row, optionally followed by 4 rows of runes typed with shift.
+// Empty lines and lines starting with # are ignored. For example:
+//
+//	`1234567890-=
+//	qwertyuiop[]\
+//	asdfghjkl;'
+//	zxcvbnm,./
+func Load(filename string) (*Layout, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var rows []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rows = append(rows, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if len(rows) > SpaceRow {
+		return New(name, rows[:SpaceRow], rows[SpaceRow:])
+	}
+	return New(name, rows, nil)
+}
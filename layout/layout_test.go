@@ -0,0 +1,66 @@
+package layout
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestBuiltinLayouts(t *testing.T) {
+	for _, name := range Builtin() {
+		l, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, ok := l.Key('E')
+		if !ok || !k.Shift || k.Finger.Hand() == Both {
+			t.Errorf("Expected E to be typed with shift in %s, got %+v", name, k)
+		}
+	}
+	l, _ := Get("qwerty")
+	expected := map[rune]Finger{
+		'q': LeftPinky, 'f': LeftIndex, 'b': LeftIndex, 'n': RightIndex, 'k': RightMiddle,
+		'/': RightPinky, '1': LeftPinky, '5': LeftIndex, '6': RightIndex, '0': RightPinky, ' ': Thumb,
+	}
+	for r, f := range expected {
+		if k, _ := l.Key(r); k.Finger != f {
+			t.Errorf("Expected %q to be typed with %s, got %s", r, f, k.Finger)
+		}
+	}
+}
+
+func TestLoadLayout(t *testing.T) {
+	f, err := ioutil.TempFile("", "layout*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, _ = f.WriteString("# my layout\n1234\nabcd\n\nefgh\nijkl\n")
+	f.Close()
+
+	l, err := Get(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, ok := l.Key('g'); !ok || k.Row != HomeRow || k.Column != 2 || k.Finger != LeftMiddle {
+		t.Errorf("Unexpected key for g: %+v", k)
+	}
+	if _, ok := l.Key('z'); ok {
+		t.Errorf("Key z should not be in layout")
+	}
+}
+
+func TestRemap(t *testing.T) {
+	qwerty, _ := Get("qwerty")
+	colemak, _ := Get("colemak")
+	remap := Remap(qwerty, colemak)
+	expected := map[rune]rune{'s': 'r', 'D': 'S', 'k': 'e', ';': 'o', 'p': ';'}
+	for from, to := range expected {
+		if remap[from] != to {
+			t.Errorf("Expected %q to be remapped to %q, got %q", from, to, remap[from])
+		}
+	}
+	if _, ok := remap['a']; ok {
+		t.Errorf("Key a is same in both layouts, and should not be remapped")
+	}
+}
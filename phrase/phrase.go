@@ -3,6 +3,7 @@ package phrase
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,31 +13,56 @@ import (
 	"strings"
 	"time"
 	"unicode/utf8"
-
-	"github.com/bunyk/gokeybr/fs"
 )
 
 func FromFile(filename string, offset, minLength int) (string, int, error) {
-	items, skipped, err := readFileLines(filename, offset)
+	items, skipped, err := LinesFromFile(filename, offset, minLength)
 	if err != nil {
 		return "", skipped, err
 	}
-	items = slice(items, minLength)
 	return strings.Join(items, "\n"), skipped, nil
 }
 
+// LinesFromFile is like FromFile, but returns text as list of lines
+func LinesFromFile(filename string, offset, minLength int) ([]string, int, error) {
+	items, skipped, err := readFileLines(filename, offset)
+	if err != nil {
+		return nil, skipped, err
+	}
+	return slice(items, minLength), skipped, nil
+}
+
 func Words(filename string, n int) (string, error) {
-	words, _, err := readFileLines(filename, 0)
+	ws, err := NewWordSource(filename)
 	if err != nil {
 		return "", err
 	}
+	return ws.Next(n), nil
+}
+
+// WordSource generates phrases from random words loaded from file,
+// so more of them could be generated when needed without reading file again
+type WordSource struct {
+	words []string
+}
+
+func NewWordSource(filename string) (*WordSource, error) {
+	words, _, err := readFileLines(filename, 0)
+	if err != nil {
+		return nil, err
+	}
 	rand.Seed(time.Now().UTC().UnixNano())
+	return &WordSource{words: words}, nil
+}
+
+// Next returns phrase of n random words
+func (ws WordSource) Next(n int) string {
 	var phrase []string
 	for i := 0; i < n; i++ {
-		w := words[rand.Intn(len(words))]
+		w := ws.words[rand.Intn(len(ws.words))]
 		phrase = append(phrase, w)
 	}
-	return strings.Join(phrase, " "), nil
+	return strings.Join(phrase, " ")
 }
 
 func readFileLines(filename string, offset int) (lines []string, skipped int, err error) {
@@ -93,7 +119,21 @@ func slice(lines []string, minLength int) []string {
 	return res
 }
 
-const ProgressFile = "progress.json"
+// ProgressStore keeps line of each text file, from which typing should continue
+type ProgressStore interface {
+	Progress(filename string) (int, error)
+	UpdateProgress(filename string, update func(line int) int) (int, error)
+}
+
+// progressStore is set by SetStore, until then progress could not be loaded or saved
+var progressStore ProgressStore
+
+var errNoStore = errors.New("Store of progress in text files is not set")
+
+// SetStore sets where progress in text files is kept
+func SetStore(s ProgressStore) {
+	progressStore = s
+}
 
 func UpdateFileProgress(filename string, linesTyped, offset int) error {
 	if filename == "-" { // Not saving for stdin
@@ -102,32 +142,29 @@ func UpdateFileProgress(filename string, linesTyped, offset int) error {
 	if linesTyped < 1 {
 		return nil // need to type at least line to update progress
 	}
-	var progressTable map[string]int
-	if err := fs.LoadJSON(ProgressFile, &progressTable); err != nil {
-		if os.IsNotExist(err) {
-			fmt.Printf("%s is not found, will be created\n", ProgressFile)
-			progressTable = make(map[string]int)
-		} else {
-			return err
-		}
+	if progressStore == nil {
+		return errNoStore
 	}
 	filename, err := filepath.Abs(filename)
 	if err != nil {
 		return err
 	}
-	if offset < 0 {
-		progressTable[filename] += linesTyped
-	} else {
-		progressTable[filename] = offset + linesTyped
+	line, err := progressStore.UpdateProgress(filename, func(line int) int {
+		if offset < 0 {
+			return line + linesTyped
+		}
+		return offset + linesTyped
+	})
+	if err != nil {
+		return err
 	}
-	fmt.Printf("Saving progress for %s to be line #%d\n", filename, progressTable[filename])
-	return fs.SaveJSON(ProgressFile, progressTable)
+	fmt.Printf("Saving progress for %s to be line #%d\n", filename, line)
+	return nil
 }
 
 func lastFileOffset(filename string) int {
-	var progressTable map[string]int
-	if err := fs.LoadJSON(ProgressFile, &progressTable); err != nil {
-		fmt.Println(err)
+	if progressStore == nil {
+		fmt.Println(errNoStore)
 		return 0
 	}
 	filename, err := filepath.Abs(filename)
@@ -135,5 +172,10 @@ func lastFileOffset(filename string) int {
 		fmt.Println(err)
 		return 0
 	}
-	return progressTable[filename]
+	line, err := progressStore.Progress(filename)
+	if err != nil {
+		fmt.Println(err)
+		return 0
+	}
+	return line
 }
@@ -0,0 +1,87 @@
+package race
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Client is connection of one racer to server
+type Client struct {
+	Name string // as given by server, could differ from requested if taken
+
+	conn   *conn
+	mu     sync.Mutex
+	racers []Racer
+}
+
+// Join connects to server at addr, like "192.168.0.2:7777"
+func Join(addr, name string) (*Client, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultPort) // port was not given
+	}
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	cl := &Client{conn: newConn(c)}
+	if err := cl.conn.send(message{Type: joinMsg, Name: name}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return cl, nil
+}
+
+// WaitStart blocks until all players joined.
+// Returns text to type, and time when race will start.
+func (c *Client) WaitStart() (string, time.Time, error) {
+	var m message
+	for m.Type != startMsg {
+		var err error
+		if m, err = c.conn.receive(); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	start := time.Now().Add(time.Duration(m.Countdown * float64(time.Second)))
+	c.racers = m.Racers
+	c.Name = m.Name
+	go c.receiveStates()
+	return m.Text, start, nil
+}
+
+func (c *Client) receiveStates() {
+	for {
+		m, err := c.conn.receive()
+		if err != nil {
+			return
+		}
+		if m.Type != stateMsg {
+			continue
+		}
+		c.mu.Lock()
+		c.racers = m.Racers
+		c.mu.Unlock()
+	}
+}
+
+// Report sends progress of this racer to server.
+// seconds is time it took to finish the text, when finished.
+func (c *Client) Report(position int, finished bool, seconds float64) error {
+	return c.conn.send(message{
+		Type:     progressMsg,
+		Position: position,
+		Finished: finished,
+		Time:     seconds,
+	})
+}
+
+// Racers returns last known state of all racers
+func (c *Client) Racers() []Racer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Racer(nil), c.racers...)
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
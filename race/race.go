@@ -0,0 +1,93 @@
+// Package race implements typing competition over local network.
+// One of the racers hosts server, that gives everyone text to type,
+// and relays progress of each racer to others.
+// Messages are sent as JSON lines over plain TCP.
+package race
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+)
+
+const DefaultPort = "7777"
+
+// Racer is state of one of the players
+type Racer struct {
+	Name     string  `json:"name"`
+	Position int     `json:"position"`
+	Finished bool    `json:"finished,omitempty"`
+	Time     float64 `json:"time,omitempty"`  // seconds it took to finish text
+	Place    int     `json:"place,omitempty"` // 1 for winner, 0 while not finished
+	Left     bool    `json:"left,omitempty"`  // disconnected before finishing
+}
+
+// Message types
+const (
+	joinMsg     = "join"     // client -> server: name of racer
+	startMsg    = "start"    // server -> client: text and countdown
+	progressMsg = "progress" // client -> server: position of racer
+	stateMsg    = "state"    // server -> client: state of all racers
+)
+
+type message struct {
+	Type      string  `json:"type"`
+	Name      string  `json:"name,omitempty"`
+	Text      string  `json:"text,omitempty"`
+	Countdown float64 `json:"countdown,omitempty"` // seconds till start of race
+	Position  int     `json:"position,omitempty"`
+	Finished  bool    `json:"finished,omitempty"`
+	Time      float64 `json:"time,omitempty"`
+	Racers    []Racer `json:"racers,omitempty"`
+}
+
+// conn sends and receives messages
+type conn struct {
+	net.Conn
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+func newConn(c net.Conn) *conn {
+	return &conn{
+		Conn: c,
+		enc:  json.NewEncoder(c),
+		dec:  json.NewDecoder(c),
+	}
+}
+
+func (c *conn) send(m message) error {
+	return c.enc.Encode(m)
+}
+
+func (c *conn) receive() (message, error) {
+	var m message
+	err := c.dec.Decode(&m)
+	return m, err
+}
+
+// Standings returns racers sorted by their place:
+// finished ones in order of finishing, then others by their progress
+func Standings(racers []Racer) []Racer {
+	res := append([]Racer(nil), racers...)
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Finished != res[j].Finished {
+			return res[i].Finished
+		}
+		if res[i].Finished {
+			return res[i].Place < res[j].Place
+		}
+		return res[i].Position > res[j].Position
+	})
+	return res
+}
+
+// Over returns true when none of the racers is still typing
+func Over(racers []Racer) bool {
+	for _, r := range racers {
+		if !r.Finished && !r.Left {
+			return false
+		}
+	}
+	return true
+}
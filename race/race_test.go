@@ -0,0 +1,110 @@
+package race
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRaceOnLoopback(t *testing.T) {
+	server, err := Listen("127.0.0.1:0", "hello", 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error)
+	go func() {
+		served <- server.Serve()
+	}()
+
+	alice, err := Join(server.Addr().String(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := Join(server.Addr().String(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Client{alice, bob} {
+		text, _, err := c.WaitStart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "hello" {
+			t.Errorf("Expected to receive text %#v, got %#v", "hello", text)
+		}
+	}
+	if alice.Name == bob.Name {
+		t.Errorf("Racers should get unique names, got %#v twice", alice.Name)
+	}
+
+	if err := bob.Report(5, true, 1.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Report(3, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	alice.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for !Over(bob.Racers()) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	standings := Standings(bob.Racers())
+	if !Over(standings) {
+		t.Fatalf("Race should be over, got %+v", standings)
+	}
+	if standings[0].Name != bob.Name || standings[0].Place != 1 {
+		t.Errorf("Bob should be first, got %+v", standings)
+	}
+	if !standings[1].Left || standings[1].Position != 3 {
+		t.Errorf("Alice should leave at position 3, got %+v", standings[1])
+	}
+	bob.Close()
+	if err := <-served; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSilentConnectionDoesNotBlockJoining(t *testing.T) {
+	JoinTimeout = 100 * time.Millisecond
+	defer func() { JoinTimeout = 10 * time.Second }()
+	server, err := Listen("127.0.0.1:0", "hello", 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error)
+	go func() {
+		served <- server.Serve()
+	}()
+
+	silent, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	alice, err := Join(server.Addr().String(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+
+	started := make(chan error)
+	go func() {
+		_, _, err := alice.WaitStart()
+		started <- err
+	}()
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Race did not start, host is blocked by silent connection")
+	}
+	if err := alice.Report(5, true, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Error(err)
+	}
+}
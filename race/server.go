@@ -0,0 +1,186 @@
+package race
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Server gives text to racers and relays progress between them
+type Server struct {
+	Text      string
+	Players   int           // race starts when that many racers joined
+	Countdown time.Duration // time between start message and start of race
+
+	listener net.Listener
+	mu       sync.Mutex
+	racers   []Racer
+	conns    []*conn
+	finished int
+}
+
+// Listen starts listening for racers on addr, like ":7777"
+func Listen(addr, text string, players int, countdown time.Duration) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		Text:      text,
+		Players:   players,
+		Countdown: countdown,
+		listener:  l,
+	}, nil
+}
+
+// Addr returns address server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve waits for all players to join, starts race and relays progress until race is over
+func (s *Server) Serve() error {
+	defer s.listener.Close()
+	for len(s.conns) < s.Players {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return err
+		}
+		if err := s.join(newConn(c)); err != nil {
+			c.Close()
+		}
+	}
+	s.listener.Close() // no more players allowed
+
+	// everyone gets start message before any progress is relayed,
+	// so messages are not written to the same connection at the same time
+	s.mu.Lock()
+	var started []int // racers that received start message
+	for i, c := range s.conns {
+		if err := c.send(message{
+			Type:      startMsg,
+			Name:      s.racers[i].Name, // so racer knows which one is them
+			Text:      s.Text,
+			Countdown: s.Countdown.Seconds(),
+			Racers:    s.racers,
+		}); err != nil {
+			s.racers[i].Left = true
+			c.Close()
+			continue
+		}
+		started = append(started, i)
+	}
+	s.mu.Unlock()
+	if len(started) < len(s.conns) {
+		s.broadcast()
+	}
+
+	var wg sync.WaitGroup
+	for _, i := range started {
+		wg.Add(1)
+		go func(i int, c *conn) {
+			defer wg.Done()
+			s.relay(i, c)
+		}(i, s.conns[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+// JoinTimeout is how long server waits for name of racer after connection,
+// so that silent connection does not block others from joining
+var JoinTimeout = 10 * time.Second
+
+// join receives name of new racer
+func (s *Server) join(c *conn) error {
+	if err := c.SetReadDeadline(time.Now().Add(JoinTimeout)); err != nil {
+		return err
+	}
+	m, err := c.receive()
+	if err != nil {
+		return err
+	}
+	if err := c.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+	if m.Type != joinMsg {
+		return fmt.Errorf("Expected %s message, got %s", joinMsg, m.Type)
+	}
+	s.racers = append(s.racers, Racer{Name: s.uniqueName(m.Name)})
+	s.conns = append(s.conns, c)
+	return nil
+}
+
+func (s *Server) uniqueName(name string) string {
+	if name == "" {
+		name = "racer"
+	}
+	unique := name
+	for i := 2; ; i++ {
+		taken := false
+		for _, r := range s.racers {
+			if r.Name == unique {
+				taken = true
+			}
+		}
+		if !taken {
+			return unique
+		}
+		unique = name + strconv.Itoa(i)
+	}
+}
+
+// relay receives progress of racer i and sends it to everyone
+func (s *Server) relay(i int, c *conn) {
+	defer c.Close()
+	for {
+		m, err := c.receive()
+		if err != nil {
+			s.leave(i)
+			return
+		}
+		if m.Type != progressMsg {
+			continue
+		}
+		s.mu.Lock()
+		r := &s.racers[i]
+		r.Position = m.Position
+		if m.Finished && !r.Finished {
+			s.finished++
+			r.Finished = true
+			r.Time = m.Time
+			r.Place = s.finished
+		}
+		s.mu.Unlock()
+		if s.broadcast() {
+			return
+		}
+	}
+}
+
+// leave marks racer i as disconnected
+func (s *Server) leave(i int) {
+	s.mu.Lock()
+	if !s.racers[i].Finished {
+		s.racers[i].Left = true
+	}
+	s.mu.Unlock()
+	s.broadcast()
+}
+
+// broadcast sends state of all racers to everyone.
+// Returns true when race is over.
+func (s *Server) broadcast() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := message{Type: stateMsg, Racers: s.racers}
+	for i, c := range s.conns {
+		if s.racers[i].Left {
+			continue
+		}
+		_ = c.send(m) // disconnect will be noticed when receiving
+	}
+	return Over(s.racers)
+}
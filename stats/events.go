@@ -0,0 +1,23 @@
+package stats
+
+// EventsLogFile stores raw keyboard events of sessions, when recording them is enabled
+const EventsLogFile = "events_log.jsonl"
+
+// KeyEvent is keyboard event as it was received by app
+type KeyEvent struct {
+	Key       string  `json:"key"`             // name of key, like "Rune" or "Backspace2"
+	Rune      string  `json:"rune,omitempty"`  // character of pressed key, when key is "Rune"
+	Typed     string  `json:"typed,omitempty"` // character remapped to simulated layout, if it differs
+	Modifiers int     `json:"mod,omitempty"`   // bit mask of Shift, Ctrl, Alt and Meta
+	Time      float64 `json:"time"`            // seconds since first key press, as they passed, with pauses
+	// Seconds since first key press by clock of session, without pauses and cut idle time, same as in timeline
+	SessionTime float64 `json:"session_time,omitempty"`
+	Position    int     `json:"pos"` // input position after event was processed
+}
+
+// eventsLogEntry is one line of EventsLogFile, which could be matched
+// to line of LogStatsFile by start time
+type eventsLogEntry struct {
+	Start  string     `json:"start"`
+	Events []KeyEvent `json:"events"`
+}
@@ -0,0 +1,161 @@
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// Sources of sessions, as they are exported
+const (
+	SourceText     = "text"     // text given by user, or words from dictionary
+	SourceTraining = "training" // text generated from stats
+	// sessions imported from other programs have name of that program as source
+)
+
+// ExportedSession is summary of session from log, for analysis in other programs
+type ExportedSession struct {
+	Start    time.Time `json:"start"`
+	Duration float64   `json:"duration"` // in seconds
+	Chars    int       `json:"chars"`
+	WPM      float64   `json:"wpm"`
+	Accuracy float64   `json:"accuracy"` // share of correct keystrokes, from 0 to 1
+	Source   string    `json:"source"`
+	Mode     string    `json:"mode,omitempty"` // drill pass
+}
+
+// ExportedNGram is aggregated stats of n-gram, for analysis in other programs
+type ExportedNGram struct {
+	NGram     string  `json:"ngram"`
+	Order     int     `json:"n"`
+	Count     int     `json:"count"` // times typed, not counting training sessions
+	Typed     int     `json:"typed"` // times typed, including training sessions
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	Duration  float64 `json:"duration"` // average of last typings, in seconds
+	WPM       float64 `json:"wpm"`
+}
+
+// inRange returns whether t is in [from, to). Zero from or to does not limit range.
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+// ExportSessions returns summaries of sessions from log, that started from from till to.
+// Zero from or to does not limit range.
+func ExportSessions(from, to time.Time) ([]ExportedSession, error) {
+	res := []ExportedSession{}
+	err := sessionsInRange(from, to, func(start time.Time, e statLogEntry) {
+		chars, duration := e.totals()
+		var wpm float64
+		if duration > 0 {
+			wpm = calcWPM(chars, duration)
+		}
+		source := SourceText
+		if e.Training {
+			source = SourceTraining
+		}
+		if e.Source != "" {
+			source = e.Source
+		}
+		res = append(res, ExportedSession{
+			Start:    start,
+			Duration: duration,
+			Chars:    chars,
+			WPM:      wpm,
+			Accuracy: e.accuracy(chars),
+			Source:   source,
+			Mode:     e.Mode,
+		})
+	})
+	return res, err
+}
+
+// fastMode is drill pass in which mistyped characters are not corrected
+const fastMode = "fast"
+
+// accuracy returns share of correct keystrokes of session, in which chars were typed.
+// In fast pass mistakes are among typed characters, in others they were corrected,
+// so each one is additional keystroke.
+func (e statLogEntry) accuracy(chars int) float64 {
+	if e.Mode == fastMode {
+		return float64(chars-len(e.Mistakes)) / float64(chars)
+	}
+	return float64(chars) / float64(chars+len(e.Mistakes)+e.Errors)
+}
+
+// sessionsInRange calls f for each session from log that started from from till to.
+// Broken sessions are skipped.
+func sessionsInRange(from, to time.Time, f func(start time.Time, e statLogEntry)) error {
+	sessionsIter, err := store.Sessions()
+	if err != nil {
+		return err
+	}
+	defer sessionsIter.Close()
+	for {
+		var logEntry statLogEntry
+		cont, err := sessionsIter.Next(&logEntry)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+		if logEntry.broken() {
+			continue // broken line
+		}
+		start, err := time.Parse(time.RFC3339, logEntry.Start)
+		if err != nil {
+			continue
+		}
+		if inRange(start, from, to) {
+			f(start, logEntry)
+		}
+	}
+}
+
+// ExportNGrams returns stats of n-grams, sorted by order and n-gram.
+// When from or to is not zero, stats are computed from sessions in that range,
+// otherwise saved stats are returned.
+func ExportNGrams(from, to time.Time) ([]ExportedNGram, error) {
+	var s *stats
+	if from.IsZero() && to.IsZero() {
+		var err error
+		if s, err = loadStats(); err != nil {
+			return nil, err
+		}
+	} else {
+		s = newStats()
+		err := sessionsInRange(from, to, func(_ time.Time, e statLogEntry) {
+			s.addLogEntry(e)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	res := make([]ExportedNGram, 0, len(s.NGrams))
+	for k, ns := range s.NGrams {
+		order := len([]rune(k))
+		duration := ns.Duration.Average(0)
+		var wpm float64
+		if duration > 0 {
+			wpm = ngramWPM(duration, order)
+		}
+		res = append(res, ExportedNGram{
+			NGram:     k,
+			Order:     order,
+			Count:     ns.Count,
+			Typed:     ns.Typed,
+			Errors:    ns.Errors,
+			ErrorRate: ns.ErrorRate(),
+			Duration:  duration,
+			WPM:       wpm,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Order != res[j].Order {
+			return res[i].Order < res[j].Order
+		}
+		return res[i].NGram < res[j].NGram
+	})
+	return res, nil
+}
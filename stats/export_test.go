@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExportRange(t *testing.T) {
+	SetStore(NewMemoryStore())
+	defer SetStore(NewJSONStore())
+
+	day := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
+	for i, text := range []string{"abcde", "edcba"} {
+		err := SaveSession(Session{
+			Start:    day.AddDate(0, 0, i),
+			Text:     []rune(text),
+			Timeline: []float64{0, 0.1, 0.3, 0.6, 0.75},
+			Mistakes: []Mistake{{Position: 2, Expected: string(text[2]), Typed: "x"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions, err := ExportSessions(day.AddDate(0, 0, 1), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || !sessions[0].Start.Equal(day.AddDate(0, 0, 1)) {
+		t.Fatalf("Expected only second session to be exported, got %+v", sessions)
+	}
+	if s := sessions[0]; s.WPM != 80 || s.Accuracy != 5.0/6 || s.Source != SourceText {
+		t.Errorf("Expected 80 wpm with 5/6 accuracy, got %+v", s)
+	}
+
+	fast := statLogEntry{Mode: fastMode, Text: "abcde", Mistakes: []Mistake{{Position: 2}}}
+	if acc := fast.accuracy(5); acc != 0.8 {
+		t.Errorf("Expected mistyped character in fast pass to be counted among typed, got accuracy %v", acc)
+	}
+
+	ngrams, err := ExportNGrams(time.Time{}, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range ngrams {
+		if n.NGram == "dc" || n.NGram == "ba" {
+			t.Errorf("N-gram %#v of session out of range should not be exported", n.NGram)
+		}
+	}
+	if all, _ := ExportNGrams(time.Time{}, time.Time{}); len(all) <= len(ngrams) {
+		t.Errorf("Expected stats of both sessions without range, got %d n-grams", len(all))
+	}
+}
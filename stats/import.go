@@ -0,0 +1,326 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importedResult is typing test result, exported by other program
+type importedResult struct {
+	Start    time.Time
+	Text     string // empty when it was not exported
+	Chars    int
+	Duration float64 // in seconds
+	Errors   int
+}
+
+// record is row of CSV file or object from JSON array, with values converted to strings
+type record map[string]string
+
+// importers convert record exported by program to result.
+// Returns false for records that could not be imported.
+var importers = map[string]func(r record) (importedResult, bool){
+	"monkeytype": importMonkeytype,
+	"keybr":      importKeybr,
+	"typeracer":  importTyperacer,
+}
+
+// ImportSources returns names of programs, which exports could be imported
+func ImportSources() []string {
+	sources := make([]string, 0, len(importers))
+	for name := range importers {
+		sources = append(sources, name)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Import adds results exported by other program to log of sessions, and updates stats.
+// Timelines of results are synthesized, as if each character took the same time,
+// so they are shown in progress and export, and ones with text are used as ghosts,
+// like sessions typed here. They are not counted in durations of n-grams, and results
+// without text are counted only in totals, as it is not known which n-grams were typed.
+// Results already imported before are skipped.
+// Returns number of imported results, and of results that could not be imported.
+func Import(source string, r io.Reader) (imported, skipped int, err error) {
+	convert, ok := importers[source]
+	if !ok {
+		return 0, 0, fmt.Errorf("Unknown source %#v, should be one of: %s", source, strings.Join(ImportSources(), ", "))
+	}
+	records, err := readRecords(r)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not read export of %s: %w", source, err)
+	}
+	if _, err := loadStats(); err != nil { // computed again from log, if they are of old version
+		return 0, 0, err
+	}
+	seen, err := importedStarts(source)
+	if err != nil {
+		return 0, 0, err
+	}
+	var entries []statLogEntry
+	for _, rec := range records {
+		res, ok := convert(rec)
+		if !ok || res.Start.IsZero() || res.Chars <= 0 || res.Duration <= 0 {
+			skipped++
+			continue
+		}
+		e := res.logEntry(source)
+		if seen[e.Start] {
+			continue // imported before
+		}
+		seen[e.Start] = true
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		return 0, skipped, nil
+	}
+	// results are usually older than sessions in log, which should be ordered by start
+	if err := store.InsertSessions(entries); err != nil {
+		return 0, skipped, err
+	}
+	imported = len(entries)
+	_, err = store.UpdateStats(func(s *stats) {
+		for _, e := range entries {
+			s.addLogEntry(e)
+		}
+	})
+	return imported, skipped, err
+}
+
+// importedStarts returns starts of sessions already imported from source
+func importedStarts(source string) (map[string]bool, error) {
+	starts := make(map[string]bool)
+	sessionsIter, err := store.Sessions()
+	if err != nil {
+		return nil, err
+	}
+	defer sessionsIter.Close()
+	for {
+		var e statLogEntry
+		cont, err := sessionsIter.Next(&e)
+		if err != nil || !cont {
+			return starts, err
+		}
+		if e.Source == source {
+			starts[e.Start] = true
+		}
+	}
+}
+
+func (res importedResult) logEntry(source string) statLogEntry {
+	e := statLogEntry{
+		Version: logVersion,
+		Start:   res.Start.UTC().Format(time.RFC3339),
+		Source:  source,
+		Errors:  res.Errors,
+	}
+	if res.Chars < 2 { // timeline could not last for duration
+		e.Chars = res.Chars
+		e.Duration = res.Duration
+		return e
+	}
+	e.Text = res.Text
+	// like in sessions typed here, time is counted from first key press
+	e.Timeline = make([]float64, res.Chars)
+	for i := range e.Timeline {
+		e.Timeline[i] = res.Duration * float64(i) / float64(res.Chars-1)
+	}
+	return e
+}
+
+func importMonkeytype(r record) (importedResult, bool) {
+	start, ok := r.time("timestamp")
+	if !ok {
+		return importedResult{}, false
+	}
+	res := importedResult{Start: start}
+	res.Duration, _ = r.number("testDuration")
+	// correct;incorrect;extra;missed
+	if charStats := r.numbers("charStats"); len(charStats) > 0 {
+		res.Chars = int(charStats[0])
+	} else if wpm, ok := r.number("wpm"); ok {
+		res.Chars = int(math.Round(wpm / WPMinCPS * res.Duration))
+	}
+	if acc, ok := r.number("acc"); ok {
+		res.Errors = errorsFromAccuracy(res.Chars, acc)
+	}
+	return res, true
+}
+
+func importKeybr(r record) (importedResult, bool) {
+	start, ok := r.time("timeStamp")
+	if !ok {
+		return importedResult{}, false
+	}
+	res := importedResult{Start: start}
+	length, _ := r.number("length")
+	res.Chars = int(length)
+	if ms, ok := r.number("time"); ok {
+		res.Duration = ms / 1000
+	} else if cpm, ok := r.number("speed"); ok && cpm > 0 {
+		res.Duration = length / cpm * 60
+	}
+	errors, _ := r.number("errors")
+	res.Errors = int(errors)
+	return res, true
+}
+
+func importTyperacer(r record) (importedResult, bool) {
+	start, ok := r.time("Date/Time (UTC)", "Date", "Timestamp")
+	if !ok {
+		return importedResult{}, false
+	}
+	// only speed is exported, so characters and time could be computed only from text
+	text := r.text("Text", "Quote")
+	wpm, ok := r.number("WPM")
+	if text == "" || !ok || wpm <= 0 {
+		return importedResult{}, false
+	}
+	res := importedResult{Start: start, Text: text, Chars: len([]rune(text))}
+	res.Duration = float64(res.Chars) / (wpm / WPMinCPS)
+	if acc, ok := r.number("Accuracy"); ok {
+		res.Errors = errorsFromAccuracy(res.Chars, acc)
+	}
+	return res, true
+}
+
+// errorsFromAccuracy returns number of mistakes, for accuracy in percents or from 0 to 1
+func errorsFromAccuracy(chars int, acc float64) int {
+	if acc > 1 {
+		acc /= 100
+	}
+	if acc <= 0 || acc >= 1 {
+		return 0
+	}
+	return int(math.Round(float64(chars) * (1 - acc) / acc))
+}
+
+// text returns value of first of given fields, that is not empty
+func (r record) text(names ...string) string {
+	for _, name := range names {
+		if v := strings.TrimSpace(r[name]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (r record) number(names ...string) (float64, bool) {
+	v := strings.TrimSuffix(r.text(names...), "%")
+	f, err := strconv.ParseFloat(v, 64)
+	return f, err == nil
+}
+
+// numbers parses list of numbers, separated by semicolons
+func (r record) numbers(name string) []float64 {
+	var res []float64
+	for _, v := range strings.Split(r[name], ";") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+// time parses milliseconds since epoch, RFC3339, or date with time in UTC
+func (r record) time(names ...string) (time.Time, bool) {
+	v := r.text(names...)
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), true
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// readRecords reads JSON array of objects, or CSV file with header
+func readRecords(r io.Reader) ([]record, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // byte order mark, added by spreadsheets
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return readJSONRecords(trimmed)
+	}
+	return readCSVRecords(bytes.NewReader(data))
+}
+
+func readJSONRecords(data []byte) ([]record, error) {
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, err
+	}
+	records := make([]record, len(objects))
+	for i, obj := range objects {
+		records[i] = make(record)
+		for k, v := range obj {
+			records[i][k] = jsonValueString(v)
+		}
+	}
+	return records, nil
+}
+
+// jsonValueString converts JSON value to string, like it would be in CSV.
+// Arrays are joined by semicolons, objects are ignored.
+func jsonValueString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = jsonValueString(item)
+		}
+		return strings.Join(parts, ";")
+	}
+	return ""
+}
+
+func readCSVRecords(r io.Reader) ([]record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec := make(record)
+		for i, v := range row {
+			if i < len(header) {
+				rec[strings.TrimSpace(header[i])] = v
+			}
+		}
+		records = append(records, rec)
+	}
+}
@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	SetStore(NewMemoryStore())
+	defer SetStore(NewJSONStore())
+
+	monkeytype := `_id,isPb,wpm,acc,rawWpm,consistency,charStats,mode,mode2,testDuration,timestamp
+a1,true,60,100,62,80,150;2;0;0,time,30,30,1700000000000
+a2,false,0,0,0,0,,time,30,0,1700000100000
+`
+	imported, skipped, err := Import("monkeytype", strings.NewReader(monkeytype))
+	if err != nil || imported != 1 || skipped != 1 {
+		t.Fatalf("Expected 1 result imported and 1 skipped, got %d, %d, %v", imported, skipped, err)
+	}
+	if imported, _, _ := Import("monkeytype", strings.NewReader(monkeytype)); imported != 0 {
+		t.Errorf("Results should not be imported twice, got %d imported", imported)
+	}
+
+	keybr := `[{"layout": "en-us", "timeStamp": "2023-11-15T10:00:00.000Z", "length": 100, "time": 20000, "errors": 4, "speed": 300}]`
+	if imported, _, err := Import("keybr", strings.NewReader(keybr)); err != nil || imported != 1 {
+		t.Fatalf("Expected keybr result to be imported, got %d, %v", imported, err)
+	}
+
+	typeracer := "Race #,WPM,Accuracy,Date/Time (UTC),Text\n1,60,0.98,2023-11-16 10:00:00,the quick fox\n"
+	if imported, _, err := Import("typeracer", strings.NewReader(typeracer)); err != nil || imported != 1 {
+		t.Fatalf("Expected typeracer result to be imported, got %d, %v", imported, err)
+	}
+
+	s := loadedStats(t)
+	if s.SessionsCount != 3 || s.TotalCharsTyped != 150+100+13 {
+		t.Errorf("Expected totals of 3 sessions with 263 characters, got %+v", s)
+	}
+	if s.TotalSessionsDuration != 30+20+2.6 {
+		t.Errorf("Expected 52.6 seconds of typing, got %f", s.TotalSessionsDuration)
+	}
+	if s.NGrams["fox"].Count != 1 || s.NGrams["a"].Count != 0 {
+		t.Errorf("Expected n-grams only of text from typeracer, got %+v", s.NGrams)
+	}
+	if d := s.NGrams["fox"].Duration; d.Length != 0 {
+		t.Errorf("Expected synthesized timeline not to be counted in n-gram durations, got %+v", d)
+	}
+	if ghost, err := GhostTimeline([]rune("the quick fox"), GhostBest); err != nil || len(ghost) != 13 || ghost[12] != 2.6 {
+		t.Errorf("Expected synthesized timeline to be used as ghost, got %v, %v", ghost, err)
+	}
+	progress, err := wpmProgress(time.Hour)
+	if err != nil || len(progress) != 1 || math.Abs(progress[0]-calcWPM(263, 52.6)) > 1e-9 {
+		t.Errorf("Expected imported sessions to be shown in progress, got %v, %v", progress, err)
+	}
+
+	if _, err := Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := loadedStats(t); rebuilt.SessionsCount != 3 || rebuilt.TotalCharsTyped != s.TotalCharsTyped {
+		t.Errorf("Expected imported sessions to be counted after rebuild, got %+v", rebuilt)
+	}
+
+	sessions, err := ExportSessions(time.Time{}, time.Time{})
+	if err != nil || len(sessions) != 3 {
+		t.Fatalf("Expected 3 sessions to be exported, got %+v, %v", sessions, err)
+	}
+	if k := sessions[1]; k.Source != "keybr" || k.WPM != 60 || k.Accuracy != 100.0/104 {
+		t.Errorf("Expected keybr session with 60 wpm and 4 errors, got %+v", k)
+	}
+}
+
+func TestImportedSessionsAreOrderedByStart(t *testing.T) {
+	dir, cleanup := withTempStore(t)
+	defer cleanup()
+	db, err := openSQLite(filepath.Join(dir, SQLiteFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for name, st := range map[string]Store{"memory": NewMemoryStore(), "json": NewJSONStore(), "sqlite": db} {
+		SetStore(st)
+		// 09:00 in UTC, so it is before 10:00 in UTC, while as text it is after
+		start := time.Date(2024, 1, 10, 11, 0, 0, 0, time.FixedZone("EET", 2*3600))
+		err := SaveSession(Session{Start: start, Text: []rune("hello"), Timeline: []float64{0, 0.1, 0.2, 0.3, 0.4}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		typeracer := "WPM,Date/Time (UTC),Text\n60,2024-01-10 10:00:00,newer text\n60,2023-11-16 10:00:00,older text\n"
+		if _, _, err := Import("typeracer", strings.NewReader(typeracer)); err != nil {
+			t.Fatal(err)
+		}
+		for n, expected := range []string{"newer text", "older text", "hello", "newer text"} {
+			s, err := LoadSession(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(s.Text) != expected {
+				t.Errorf("%s: expected session #%d to be %#v, got %#v", name, n, expected, string(s.Text))
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bunyk/gokeybr/layout"
+)
+
+// intervalStat averages time between key presses
+type intervalStat struct {
+	total  float64
+	weight float64
+}
+
+func (is *intervalStat) add(t, weight float64) {
+	is.total += t * weight
+	is.weight += weight
+}
+
+func (is intervalStat) wpm() float64 {
+	if is.weight == 0 {
+		return 0
+	}
+	return calcWPM(1, is.total/is.weight)
+}
+
+// weight is how many times n-gram was typed
+func (ns ngramStat) weight() float64 {
+	if ns.Typed > 0 {
+		return float64(ns.Typed)
+	}
+	return float64(ns.Count) // stats saved before typings were counted
+}
+
+// lowestOrder returns lowest order of n-grams, not less than min, that we have stats for
+func (s stats) lowestOrder(min int) int {
+	order := 0
+	for k := range s.NGrams {
+		n := utf8.RuneCountInString(k)
+		if n >= min && (order == 0 || n < order) {
+			order = n
+		}
+	}
+	return order
+}
+
+// keyIntervals returns average time of pressing each key
+func (s stats) keyIntervals() map[rune]*intervalStat {
+	res := make(map[rune]*intervalStat)
+	for k, ns := range s.ngrams(s.lowestOrder(1)) {
+		if ns.Duration.Length == 0 {
+			continue // typed only in imported sessions, so time is not known
+		}
+		runes := []rune(k)
+		t := ns.Duration.Average(0) / float64(len(runes))
+		for _, r := range runes {
+			if res[r] == nil {
+				res[r] = &intervalStat{}
+			}
+			res[r].add(t, ns.weight())
+		}
+	}
+	return res
+}
+
+// keyErrors returns how many times each key was mistyped, and how many times it was typed
+func (s stats) keyErrors() (errors, typed map[rune]float64) {
+	errors = make(map[rune]float64)
+	typed = make(map[rune]float64)
+	for k, ns := range s.ngrams(s.lowestOrder(1)) {
+		runes := []rune(k)
+		last := runes[len(runes)-1] // errors are counted for last character of n-gram
+		errors[last] += float64(ns.Errors)
+		typed[last] += ns.weight()
+	}
+	return errors, typed
+}
+
+// KeyHeat returns how bad each key of layout is typed: from 0 for the best key, to 1 for the worst.
+// Keys are compared by average time of pressing them, or by error rate.
+func KeyHeat(l *layout.Layout, byErrors bool) (map[layout.Position]float64, error) {
+	stats, err := loadStats()
+	if err != nil {
+		return nil, err
+	}
+	total := make(map[layout.Position]float64)
+	weight := make(map[layout.Position]float64)
+	add := func(r rune, value, w float64) {
+		if key, ok := l.Key(r); ok { // shifted and unshifted runes are on one key
+			total[key.Position] += value
+			weight[key.Position] += w
+		}
+	}
+	if byErrors {
+		errors, typed := stats.keyErrors()
+		for r, t := range typed {
+			add(r, errors[r], t)
+		}
+	} else {
+		for r, is := range stats.keyIntervals() {
+			add(r, is.total, is.weight)
+		}
+	}
+
+	heat := make(map[layout.Position]float64)
+	min, max := math.Inf(1), math.Inf(-1)
+	for p, t := range total {
+		if weight[p] == 0 {
+			continue
+		}
+		heat[p] = t / weight[p]
+		min = math.Min(min, heat[p])
+		max = math.Max(max, heat[p])
+	}
+	for p, h := range heat {
+		if max > min {
+			heat[p] = (h - min) / (max - min)
+		} else {
+			heat[p] = 0
+		}
+	}
+	return heat, nil
+}
+
+type layoutStats struct {
+	fingers      map[layout.Finger]*intervalStat
+	sameFinger   intervalStat // bigrams of different keys typed with one finger
+	otherBigrams intervalStat
+	alternating  intervalStat // bigrams typed with different hands
+	sameHand     intervalStat
+	rowJumps     map[int]*intervalStat // bigrams typed with one hand, by number of rows between keys
+}
+
+// layoutStats aggregates n-gram stats by keys positions in layout.
+// N-gram of order n takes n intervals between key presses, and each of them
+// is approximated by average.
+func (s stats) layoutStats(l *layout.Layout) layoutStats {
+	ls := layoutStats{
+		fingers:  make(map[layout.Finger]*intervalStat),
+		rowJumps: make(map[int]*intervalStat),
+	}
+	for _, f := range layout.Fingers {
+		ls.fingers[f] = &intervalStat{}
+	}
+	for r, is := range s.keyIntervals() {
+		if key, ok := l.Key(r); ok && is.weight > 0 {
+			ls.fingers[key.Finger].add(is.total/is.weight, is.weight)
+		}
+	}
+	for k, ns := range s.ngrams(s.lowestOrder(2)) {
+		if ns.Duration.Length == 0 {
+			continue // typed only in imported sessions, so time is not known
+		}
+		runes := []rune(k)
+		t := ns.Duration.Average(0) / float64(len(runes))
+		for i := 1; i < len(runes); i++ {
+			k1, ok1 := l.Key(runes[i-1])
+			k2, ok2 := l.Key(runes[i])
+			if !ok1 || !ok2 {
+				continue
+			}
+			ls.addBigram(k1, k2, t, ns.weight())
+		}
+	}
+	return ls
+}
+
+func (ls *layoutStats) addBigram(k1, k2 layout.Key, t, weight float64) {
+	sameKey := k1.Position == k2.Position
+	if k1.Finger == k2.Finger && !sameKey {
+		ls.sameFinger.add(t, weight)
+	} else {
+		ls.otherBigrams.add(t, weight)
+	}
+	h1, h2 := k1.Finger.Hand(), k2.Finger.Hand()
+	if h1 == layout.Both || h2 == layout.Both {
+		return // space could be typed by any hand
+	}
+	if h1 != h2 {
+		ls.alternating.add(t, weight)
+		return
+	}
+	ls.sameHand.add(t, weight)
+	if sameKey {
+		return
+	}
+	jump := k1.Row - k2.Row
+	if jump < 0 {
+		jump = -jump
+	}
+	if ls.rowJumps[jump] == nil {
+		ls.rowJumps[jump] = &intervalStat{}
+	}
+	ls.rowJumps[jump].add(t, weight)
+}
+
+func percent(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return part / total * 100.0
+}
+
+// LayoutReport shows how typing speed depends on fingers and hands used to type
+func LayoutReport(l *layout.Layout) (string, error) {
+	stats, err := loadStats()
+	if err != nil {
+		return "", err
+	}
+	if len(stats.NGrams) == 0 {
+		return "", fmt.Errorf("No stats yet")
+	}
+	res := make([]string, 0)
+	print := func(f string, args ...interface{}) {
+		res = append(res, fmt.Sprintf(f, args...))
+	}
+	ls := stats.layoutStats(l)
+
+	print("\nFinger stats for %s layout:\n", l.Name)
+	print("Finger       |     Speed | Keystrokes\n")
+	totalKeys := 0.0
+	for _, fs := range ls.fingers {
+		totalKeys += fs.weight
+	}
+	for _, f := range layout.Fingers {
+		fs := ls.fingers[f]
+		print("%-12s | %5.1f wpm | %5.1f%%\n", f, fs.wpm(), percent(fs.weight, totalKeys))
+	}
+
+	totalBigrams := ls.sameFinger.weight + ls.otherBigrams.weight
+	if totalBigrams == 0 {
+		return strings.Join(res, ""), nil
+	}
+	print("\nSame finger bigrams: %.1f%% typed at %.1f wpm, other bigrams at %.1f wpm\n",
+		percent(ls.sameFinger.weight, totalBigrams), ls.sameFinger.wpm(), ls.otherBigrams.wpm(),
+	)
+	print("Hand alternation: %.1f%% typed at %.1f wpm, same hand at %.1f wpm\n",
+		percent(ls.alternating.weight, ls.alternating.weight+ls.sameHand.weight),
+		ls.alternating.wpm(), ls.sameHand.wpm(),
+	)
+	print("\nRow jumps within one hand:\n")
+	print("Rows |     Speed | Bigrams\n")
+	for jump := 0; jump <= layout.BottomRow; jump++ {
+		if rs := ls.rowJumps[jump]; rs != nil {
+			print("%4d | %5.1f wpm | %5.1f%%\n", jump, rs.wpm(), percent(rs.weight, ls.sameHand.weight))
+		}
+	}
+	return strings.Join(res, ""), nil
+}
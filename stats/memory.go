@@ -0,0 +1,132 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryStore keeps everything in memory, and forgets it when program exits
+type memoryStore struct {
+	mu       sync.Mutex
+	sessions []statLogEntry
+	events   [][]KeyEvent // of each session
+	stats    *stats
+	progress map[string]int
+}
+
+// NewMemoryStore returns empty store that keeps data only in memory, useful for tests
+func NewMemoryStore() Store {
+	return &memoryStore{stats: newStats(), progress: make(map[string]int)}
+}
+
+func (m *memoryStore) AppendSession(e statLogEntry, events []KeyEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions = append(m.sessions, e)
+	m.events = append(m.events, events)
+	return nil
+}
+
+func (m *memoryStore) InsertSessions(entries []statLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := append(m.sessions, entries...)
+	events := append(m.events, make([][]KeyEvent, len(entries))...)
+	order := orderByStart(len(sessions), func(i int) (time.Time, bool) {
+		t, err := time.Parse(time.RFC3339, sessions[i].Start)
+		return t, err == nil
+	})
+	// new slices, so iterators that are already started do not see changes
+	m.sessions = make([]statLogEntry, len(order))
+	m.events = make([][]KeyEvent, len(order))
+	for i, j := range order {
+		m.sessions[i], m.events[i] = sessions[j], events[j]
+	}
+	return nil
+}
+
+func (m *memoryStore) Sessions() (sessionIterator, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// sessions appended while iterating are not seen, like in other stores
+	return &memorySessions{sessions: m.sessions[:len(m.sessions):len(m.sessions)]}, nil
+}
+
+func (m *memoryStore) SessionTimelines(f func(timeline []float64)) error {
+	return sessionTimelines(m, f)
+}
+
+type memorySessions struct {
+	sessions []statLogEntry
+	next     int
+}
+
+func (it *memorySessions) Next(e *statLogEntry) (bool, error) {
+	if it.next >= len(it.sessions) {
+		return false, nil
+	}
+	*e = it.sessions[it.next]
+	it.next++
+	return true, nil
+}
+
+func (it *memorySessions) Close() {}
+
+func (m *memoryStore) Events(start string, skip int) ([]KeyEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, e := range m.sessions {
+		if e.Start != start {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		return m.events[i], nil
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) LoadStats() (*stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats.copy(), nil
+}
+
+func (m *memoryStore) UpdateStats(update func(s *stats)) (*stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats.copy()
+	update(s)
+	m.stats = s.copy()
+	return s, nil
+}
+
+func (m *memoryStore) ReplaceStats(build func() (*stats, error)) error {
+	s, err := build() // not locked, as it reads sessions
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = s.copy()
+	return nil
+}
+
+func (m *memoryStore) Progress(filename string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.progress[filename], nil
+}
+
+func (m *memoryStore) UpdateProgress(filename string, update func(line int) int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progress[filename] = update(m.progress[filename])
+	return m.progress[filename], nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
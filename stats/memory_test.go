@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	SetStore(NewMemoryStore())
+	defer SetStore(NewJSONStore())
+
+	events := []KeyEvent{{Key: "Rune", Rune: "a"}}
+	timeline := []float64{0, 0.1, 0.3, 0.6, 1.0, 1.5}
+	for _, ev := range [][]KeyEvent{nil, events} {
+		if err := SaveSession(Session{Text: []rune("abcdef"), Timeline: timeline, Events: ev}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := loadedStats(t)
+	if saved.SessionsCount != 2 || saved.NGrams["bc"].Count != 2 {
+		t.Errorf("Expected stats of 2 sessions, got %+v", saved)
+	}
+	last, err := LoadSession(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(last.Events, events) {
+		t.Errorf("Expected events %+v of last session, got %+v", events, last.Events)
+	}
+	ghost, err := GhostTimeline([]rune("abcdef"), GhostBest)
+	if err != nil || !reflect.DeepEqual(ghost, timeline) {
+		t.Errorf("Expected ghost %v, got %v, %v", timeline, ghost, err)
+	}
+
+	if _, err := Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := loadedStats(t); !reflect.DeepEqual(saved, rebuilt) {
+		t.Errorf("Expected rebuilt stats %+v to be same as saved %+v", rebuilt, saved)
+	}
+
+	line, err := store.UpdateProgress("text.txt", func(line int) int { return line + 3 })
+	if err != nil || line != 3 {
+		t.Errorf("Expected progress to be line 3, got %d, %v", line, err)
+	}
+	if line, _ := store.Progress("text.txt"); line != 3 {
+		t.Errorf("Expected saved progress to be line 3, got %d", line)
+	}
+}
@@ -0,0 +1,157 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// migration upgrades JSON object saved with one version of schema to next version.
+// nil migration is used when only version changes, as older objects are valid in new one.
+type migration func(obj map[string]interface{}) error
+
+const versionKey = "version"
+
+// statsMigrations[i] upgrades stats file from version i, so current version is their count
+var statsMigrations = []migration{
+	// 0 -> 1: only trigrams were counted, now n-grams of all orders are.
+	// Trigram was timed till next character was typed, now from previous one,
+	// so old durations are dropped, and computed again from log when stats are loaded.
+	func(obj map[string]interface{}) error {
+		ngrams, _ := obj["NGrams"].(map[string]interface{})
+		if ngrams == nil {
+			ngrams = make(map[string]interface{})
+			obj["NGrams"] = ngrams
+		}
+		if trigrams, ok := obj["Trigrams"].(map[string]interface{}); ok {
+			for k, v := range trigrams {
+				if tr, ok := v.(map[string]interface{}); ok {
+					delete(tr, "d")
+				}
+				ngrams[k] = v
+			}
+		}
+		delete(obj, "Trigrams")
+		return nil
+	},
+}
+
+// logMigrations[i] upgrades line of sessions log from version i
+var logMigrations = []migration{
+	// 0 -> 1: training flag is saved, sessions before are considered regular
+	nil,
+	// 1 -> 2: sessions imported from other programs are saved, with source and totals
+	nil,
+}
+
+var statsVersion = len(statsMigrations)
+var logVersion = len(logMigrations)
+
+// migrate upgrades JSON object in data, saved with some version of schema, to current version.
+// nil migrations only change version, so when all needed are nil, data is returned as is.
+// Returns version data was saved with.
+// Refuses to load data saved by newer version of program, so it is not corrupted when saved back.
+func migrate(data []byte, name string, migrations []migration) ([]byte, int, error) {
+	var saved struct {
+		Version interface{} `json:"version"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, 0, err
+	}
+	version := 0
+	if saved.Version != nil {
+		f, ok := saved.Version.(float64)
+		if !ok {
+			return nil, 0, fmt.Errorf("Invalid schema version %v in %s", saved.Version, name)
+		}
+		version = int(f)
+	}
+	current := len(migrations)
+	if version > current {
+		return nil, version, fmt.Errorf(
+			"%s is saved by newer version of gokeybr (schema version %d, supported %d), please upgrade",
+			name, version, current,
+		)
+	}
+	pending := migrations[version:]
+	for len(pending) > 0 && pending[0] == nil {
+		pending = pending[1:]
+	}
+	if len(pending) == 0 {
+		return data, version, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, version, err
+	}
+	for v := current - len(pending); v < current; v++ {
+		if migrations[v] == nil {
+			continue
+		}
+		if err := migrations[v](obj); err != nil {
+			return nil, version, fmt.Errorf("Could not upgrade %s from schema version %d: %s", name, v, err)
+		}
+	}
+	obj[versionKey] = current
+	data, err := json.Marshal(obj)
+	return data, version, err
+}
+
+func (s *stats) UnmarshalJSON(data []byte) error {
+	data, version, err := migrate(data, StatsFile, statsMigrations)
+	if err != nil {
+		return err
+	}
+	type plain stats // without UnmarshalJSON method, to not call it recursively
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	s.Version = statsVersion
+	s.outdated = version == 0 // durations were measured differently, and are dropped
+	return nil
+}
+
+func (e *statLogEntry) UnmarshalJSON(data []byte) error {
+	data, _, err := migrate(data, LogStatsFile, logMigrations)
+	if err != nil {
+		return err
+	}
+	type plain statLogEntry
+	if err := json.Unmarshal(data, (*plain)(e)); err != nil {
+		return err
+	}
+	e.Version = logVersion
+	return nil
+}
+
+func (w *Window) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Length int   `json:"l"`
+		Index  int   `json:"i"`
+		Values []int `json:"v"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.Values) == Capacity {
+		w.Length, w.Index = v.Length, v.Index
+		copy(w.Values[:], v.Values)
+		return nil
+	}
+	// window was saved with other capacity, its last values are kept
+	*w = Window{}
+	size := len(v.Values)
+	if size == 0 {
+		return nil
+	}
+	oldest := 0
+	if v.Length >= size { // buffer is full, so next value would overwrite the oldest one
+		v.Length = size
+		if v.Index > 0 {
+			oldest = v.Index % size
+		}
+	}
+	for i := 0; i < v.Length; i++ {
+		w.appendValue(v.Values[(oldest+i)%size])
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatsMigration(t *testing.T) {
+	old := `{"TotalCharsTyped": 3, "Trigrams": {"abc": {"c": 2, "d": {"l": 1, "i": 1, "v": [300,0,0,0,0,0,0,0,0,0]}}}}`
+	var s stats
+	if err := json.Unmarshal([]byte(old), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != statsVersion || s.TotalCharsTyped != 3 {
+		t.Errorf("Expected stats to be upgraded to version %d, got %+v", statsVersion, s)
+	}
+	if ns := s.NGrams["abc"]; ns.Count != 2 || ns.Duration.Length != 0 {
+		t.Errorf("Expected trigrams to be moved to n-grams without durations, got %+v", s.NGrams)
+	}
+	if !s.outdated {
+		t.Errorf("Expected stats of version 0 to be marked for computing again")
+	}
+}
+
+func TestOldStatsAreRebuiltFromLog(t *testing.T) {
+	dir, cleanup := withTempStore(t)
+	defer cleanup()
+
+	// trigram "abc" was timed from "a" till "d" was typed
+	old := `{"TotalCharsTyped": 5, "TotalSessionsDuration": 1, "SessionsCount": 1,
+		"Trigrams": {"abc": {"c": 1, "d": {"l": 1, "i": 1, "v": [600,0,0,0,0,0,0,0,0,0]}}}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, StatsFile), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"start": "2020-01-01T00:00:00Z", "text": "abcde", "timeline": [0, 0.1, 0.3, 0.6, 1.0]}` + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, LogStatsFile), []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := loadStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.NGrams["bcd"].Duration.Average(0); got != 0.6 {
+		t.Errorf("Expected trigram to be timed from previous character, got %f", got)
+	}
+	if s.NGrams["abc"].Count != 1 || s.SessionsCount != 1 || s.TotalCharsTyped != 5 {
+		t.Errorf("Expected counts of old stats to be kept, got %+v", s)
+	}
+	if _, err := os.Stat(filepath.Join(dir, StatsFile+".bak")); err != nil {
+		t.Errorf("Expected old stats to be backed up: %v", err)
+	}
+
+	SetStore(NewJSONStore()) // load from file, not from cache
+	saved, err := loadStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.outdated || saved.Version != statsVersion || !reflect.DeepEqual(saved.NGrams, s.NGrams) {
+		t.Errorf("Expected rebuilt stats to be saved, got %+v", saved)
+	}
+}
+
+func TestOldStatsAreNotRebuiltFromPartialLog(t *testing.T) {
+	dir, cleanup := withTempStore(t)
+	defer cleanup()
+
+	old := `{"TotalCharsTyped": 500, "TotalSessionsDuration": 100, "SessionsCount": 10,
+		"Trigrams": {"abc": {"c": 10, "d": {"l": 1, "i": 1, "v": [600,0,0,0,0,0,0,0,0,0]}}}}`
+	path := filepath.Join(dir, StatsFile)
+	if err := ioutil.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"start": "2020-01-01T00:00:00Z", "text": "abcde", "timeline": [0, 0.1, 0.3, 0.6, 1.0]}` + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, LogStatsFile), []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadStats(); err == nil || !strings.Contains(err.Error(), "stats rebuild") {
+		t.Errorf("Expected stats not to be upgraded from log with only 1 of 10 sessions, got %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != old {
+		t.Errorf("Expected old stats to be left as they are, got %s", data)
+	}
+}
+
+func TestNewerSchemaIsRefused(t *testing.T) {
+	var s stats
+	err := json.Unmarshal([]byte(`{"version": 1000, "NGrams": {}}`), &s)
+	if err == nil || !strings.Contains(err.Error(), "newer version") {
+		t.Errorf("Expected stats of newer version to be refused, got %v", err)
+	}
+	var e statLogEntry
+	err = json.Unmarshal([]byte(`{"version": 1000, "text": "abc"}`), &e)
+	if err == nil {
+		t.Errorf("Expected log entry of newer version to be refused")
+	}
+}
+
+func TestWindowOfOtherCapacityIsResized(t *testing.T) {
+	var w Window
+	if err := json.Unmarshal([]byte(`{"l": 2, "i": 2, "v": [1000, 3000, 0]}`), &w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Length != 2 || w.Average(0) != 2 {
+		t.Errorf("Expected values of smaller window to be kept, got %+v", w)
+	}
+
+	// full window of 12 values, oldest is 0, newest is 11
+	values := []string{"10", "11", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+	data := `{"l": 12, "i": 2, "v": [` + strings.Join(values, ", ") + `]}`
+	if err := json.Unmarshal([]byte(data), &w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Length != Capacity || math.Abs(w.Average(0)-0.0065) > 1e-9 {
+		t.Errorf("Expected last %d values of bigger window to be kept, got %+v", Capacity, w)
+	}
+	w.Append(0.01)
+	if math.Abs(w.Average(0)-0.0073) > 1e-9 {
+		t.Errorf("Expected oldest value to be replaced by next one, got %+v", w)
+	}
+}
+
+func TestLogEntryOfOlderVersionIsLoaded(t *testing.T) {
+	var e statLogEntry
+	if err := json.Unmarshal([]byte(`{"start": "2020-01-01T00:00:00Z", "text": "ab", "timeline": [0, 1]}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Version != logVersion || e.Text != "ab" || len(e.Timeline) != 2 {
+		t.Errorf("Expected entry to be loaded with current version, got %+v", e)
+	}
+}
@@ -0,0 +1,115 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// LoadSession reads session number n (counting from 1) from log.
+// When n is 0, last session is loaded.
+// Raw key events are loaded too, if they were recorded.
+func LoadSession(n int) (Session, error) {
+	sessionsIter, err := store.Sessions()
+	if err != nil {
+		return Session{}, err
+	}
+	defer sessionsIter.Close()
+
+	var found statLogEntry
+	sameStart := 0 // number of sessions before found, that were saved with the same start
+	count := 0
+	for n == 0 || count < n {
+		var logEntry statLogEntry
+		cont, err := sessionsIter.Next(&logEntry)
+		if err != nil {
+			return Session{}, err
+		}
+		if !cont {
+			break
+		}
+		count++
+		if logEntry.Start == found.Start {
+			sameStart++
+		} else {
+			sameStart = 0
+		}
+		found = logEntry
+	}
+	if count == 0 || count < n {
+		return Session{}, fmt.Errorf("There is no session #%d in log, only %d sessions saved", n, count)
+	}
+	if found.Text == "" {
+		return Session{}, fmt.Errorf("Session was imported from %s without text, so it could not be loaded", found.Source)
+	}
+	start, err := time.Parse(time.RFC3339, found.Start)
+	if err != nil {
+		return Session{}, err
+	}
+	s := Session{
+		Start:    start,
+		Text:     []rune(found.Text),
+		Timeline: found.Timeline,
+		Mistakes: found.Mistakes,
+		Mode:     found.Mode,
+		Gaps:     found.Gaps,
+		Training: found.Training,
+	}
+	s.Events, err = store.Events(found.Start, sameStart)
+	return s, err
+}
+
+// Kinds of ghost timelines
+const (
+	GhostBest    = "best"
+	GhostAverage = "average"
+)
+
+// GhostTimeline returns timeline of previous session with the same text,
+// to race against it. kind is GhostBest for the fastest session,
+// or GhostAverage for timeline averaged over all of them.
+// Returns nil if text was never typed till the end.
+func GhostTimeline(text []rune, kind string) ([]float64, error) {
+	if kind != GhostBest && kind != GhostAverage {
+		return nil, fmt.Errorf("Unknown ghost %#v, should be %#v or %#v", kind, GhostBest, GhostAverage)
+	}
+	if len(text) == 0 {
+		return nil, nil
+	}
+	sessionsIter, err := store.Sessions()
+	if err != nil {
+		return nil, err
+	}
+	defer sessionsIter.Close()
+
+	target := string(text)
+	var best []float64
+	sum := make([]float64, len(text))
+	count := 0
+	for {
+		var logEntry statLogEntry
+		cont, err := sessionsIter.Next(&logEntry)
+		if err != nil {
+			return nil, err
+		}
+		if !cont {
+			break
+		}
+		if logEntry.Text != target || len(logEntry.Timeline) != len(text) {
+			continue
+		}
+		count++
+		for i, t := range logEntry.Timeline {
+			sum[i] += t
+		}
+		if best == nil || logEntry.Timeline[len(text)-1] < best[len(text)-1] {
+			best = logEntry.Timeline
+		}
+	}
+	if count == 0 || kind == GhostBest {
+		return best, nil
+	}
+	for i := range sum {
+		sum[i] /= float64(count)
+	}
+	return sum, nil
+}
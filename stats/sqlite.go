@@ -0,0 +1,546 @@
+package stats
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/bunyk/gokeybr/fs"
+	_ "modernc.org/sqlite" // pure Go driver, so no C compiler is needed
+)
+
+// SQLiteFile is database of SQLite store, in data directory of profile
+const SQLiteFile = "gokeybr.db"
+
+// sqliteVersion is version of database schema, kept in user_version pragma
+const sqliteVersion = 4
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	id       INTEGER PRIMARY KEY,
+	start    TEXT NOT NULL,
+	version  INTEGER NOT NULL,
+	text     TEXT NOT NULL,
+	timeline TEXT NOT NULL, -- JSON arrays, as they are only read whole
+	mistakes TEXT,
+	gaps     TEXT,
+	mode     TEXT NOT NULL DEFAULT '',
+	training INTEGER NOT NULL DEFAULT 0,
+	chars    INTEGER NOT NULL,
+	duration REAL NOT NULL,
+	source   TEXT NOT NULL DEFAULT '', -- program session was imported from
+	errors   INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS sessions_start ON sessions (start);
+-- starts are saved with time zone of computer, so they are compared as time, not as text
+CREATE INDEX IF NOT EXISTS sessions_time ON sessions (julianday(start), id);
+
+CREATE TABLE IF NOT EXISTS keystrokes (
+	session_id   INTEGER NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+	seq          INTEGER NOT NULL,
+	key          TEXT NOT NULL,
+	rune         TEXT NOT NULL DEFAULT '',
+	mod          INTEGER NOT NULL DEFAULT 0,
+	time         REAL NOT NULL, -- as it passed, with pauses
+	pos          INTEGER NOT NULL,
+	session_time REAL NOT NULL DEFAULT 0, -- as in timeline
+	typed        TEXT NOT NULL DEFAULT '', -- rune remapped to simulated layout
+	PRIMARY KEY (session_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS ngrams (
+	ngram    TEXT PRIMARY KEY,
+	n        INTEGER NOT NULL,
+	count    INTEGER NOT NULL,
+	typed    INTEGER NOT NULL,
+	errors   INTEGER NOT NULL,
+	duration TEXT NOT NULL -- JSON of Window
+);
+CREATE INDEX IF NOT EXISTS ngrams_n ON ngrams (n);
+
+CREATE TABLE IF NOT EXISTS totals (
+	id       INTEGER PRIMARY KEY CHECK (id = 1),
+	chars    INTEGER NOT NULL,
+	duration REAL NOT NULL,
+	sessions INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS progress (
+	file TEXT PRIMARY KEY,
+	line INTEGER NOT NULL
+);
+`
+
+// sqliteStore keeps sessions, their key events and aggregated stats in SQLite database
+type sqliteStore struct {
+	db *sql.DB
+	// progress in text files is shared by all layouts, like in JSON store,
+	// so when layout is simulated, it is kept in database of native layout
+	progressDB *sql.DB
+}
+
+// OpenSQLite opens SQLite store in data directory of profile, creating it if needed
+func OpenSQLite() (Store, error) {
+	path, err := fs.DataFilePath(layoutFile(SQLiteFile))
+	if err != nil {
+		return nil, err
+	}
+	st, err := openSQLite(path)
+	if err != nil || layoutFile(SQLiteFile) == SQLiteFile {
+		return st, err
+	}
+	path, err = fs.DataFilePath(SQLiteFile)
+	if err != nil {
+		st.Close()
+		return nil, err
+	}
+	native, err := openSQLite(path)
+	if err != nil {
+		st.Close()
+		return nil, err
+	}
+	st.progressDB = native.db
+	return st, nil
+}
+
+func openSQLite(path string) (*sqliteStore, error) {
+	// transactions take write lock at once, so that stats could not be changed between load and save
+	dsn := (&url.URL{
+		Scheme: "file",
+		Opaque: path,
+		RawQuery: "_txlock=immediate" +
+			"&_pragma=busy_timeout(5000)" +
+			"&_pragma=journal_mode(WAL)" +
+			"&_pragma=foreign_keys(1)",
+	}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := initSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Could not open %s: %w", path, err)
+	}
+	return &sqliteStore{db: db, progressDB: db}, nil
+}
+
+func initSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > sqliteVersion {
+		return fmt.Errorf(
+			"database has schema version %d, but only %d is supported, it was probably written by newer version of gokeybr",
+			version, sqliteVersion,
+		)
+	}
+	if version == 1 { // sessions imported from other programs were added
+		_, err := db.Exec(`
+			ALTER TABLE sessions ADD COLUMN source TEXT NOT NULL DEFAULT '';
+			ALTER TABLE sessions ADD COLUMN errors INTEGER NOT NULL DEFAULT 0;
+		`)
+		if err != nil {
+			return err
+		}
+	}
+	if version == 1 || version == 2 { // time of key events without pauses was added
+		_, err := db.Exec("ALTER TABLE keystrokes ADD COLUMN session_time REAL NOT NULL DEFAULT 0")
+		if err != nil {
+			return err
+		}
+	}
+	if version >= 1 && version <= 3 { // remapped rune of key events was added
+		_, err := db.Exec("ALTER TABLE keystrokes ADD COLUMN typed TEXT NOT NULL DEFAULT ''")
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteVersion))
+	return err
+}
+
+func (st *sqliteStore) AppendSession(e statLogEntry, events []KeyEvent) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	timeline := jsonText(e.Timeline)
+	if timeline == nil { // imported session with totals only
+		timeline = "[]"
+	}
+	chars, duration := e.totals()
+	res, err := tx.Exec(
+		`INSERT INTO sessions (start, version, text, timeline, mistakes, gaps, mode, training, chars, duration, source, errors)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Start, e.Version, e.Text, timeline, jsonText(e.Mistakes), jsonText(e.Gaps),
+		e.Mode, e.Training, chars, duration, e.Source, e.Errors,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	insert, err := tx.Prepare(
+		"INSERT INTO keystrokes (session_id, seq, key, rune, mod, time, pos, session_time, typed) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	for i, ev := range events {
+		if _, err := insert.Exec(id, i, ev.Key, ev.Rune, ev.Modifiers, ev.Time, ev.Position, ev.SessionTime, ev.Typed); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// jsonText encodes value for JSON column, nil slices are stored as NULL
+func jsonText(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return string(data)
+}
+
+func (st *sqliteStore) InsertSessions(entries []statLogEntry) error {
+	for _, e := range entries { // sessions are read ordered by start, so they could be added at the end
+		if err := st.AppendSession(e, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (st *sqliteStore) Sessions() (sessionIterator, error) {
+	rows, err := st.db.Query(
+		`SELECT start, version, text, timeline, mistakes, gaps, mode, training, chars, duration, source, errors
+		FROM sessions ORDER BY julianday(start), id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteSessions{rows}, nil
+}
+
+func (st *sqliteStore) SessionTimelines(f func(timeline []float64)) error {
+	rows, err := st.db.Query("SELECT timeline FROM sessions WHERE timeline != '[]' ORDER BY julianday(start), id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		var timeline []float64
+		if err := json.Unmarshal([]byte(data), &timeline); err != nil {
+			return err
+		}
+		f(timeline)
+	}
+	return rows.Err()
+}
+
+type sqliteSessions struct {
+	rows *sql.Rows
+}
+
+func (s sqliteSessions) Next(e *statLogEntry) (bool, error) {
+	if !s.rows.Next() {
+		return false, s.rows.Err()
+	}
+	var timeline string
+	var mistakes, gaps sql.NullString
+	*e = statLogEntry{}
+	err := s.rows.Scan(
+		&e.Start, &e.Version, &e.Text, &timeline, &mistakes, &gaps, &e.Mode, &e.Training,
+		&e.Chars, &e.Duration, &e.Source, &e.Errors,
+	)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(timeline), &e.Timeline); err != nil {
+		return false, err
+	}
+	if len(e.Timeline) > 0 { // totals are saved in log only for imported sessions without timeline
+		e.Chars, e.Duration = 0, 0
+	}
+	if mistakes.Valid {
+		if err := json.Unmarshal([]byte(mistakes.String), &e.Mistakes); err != nil {
+			return false, err
+		}
+	}
+	if gaps.Valid {
+		if err := json.Unmarshal([]byte(gaps.String), &e.Gaps); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (s sqliteSessions) Close() {
+	s.rows.Close()
+}
+
+func (st *sqliteStore) Events(start string, skip int) ([]KeyEvent, error) {
+	rows, err := st.db.Query(
+		`SELECT key, rune, mod, time, pos, session_time, typed FROM keystrokes
+		WHERE session_id = (SELECT id FROM sessions WHERE start = ? ORDER BY id LIMIT 1 OFFSET ?)
+		ORDER BY seq`,
+		start, skip,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []KeyEvent
+	for rows.Next() {
+		var ev KeyEvent
+		if err := rows.Scan(&ev.Key, &ev.Rune, &ev.Modifiers, &ev.Time, &ev.Position, &ev.SessionTime, &ev.Typed); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// querier is what is common for database and transaction
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (st *sqliteStore) LoadStats() (*stats, error) {
+	return loadSQLiteStats(st.db)
+}
+
+func loadSQLiteStats(q querier) (*stats, error) {
+	s := newStats()
+	err := q.QueryRow("SELECT chars, duration, sessions FROM totals WHERE id = 1").Scan(
+		&s.TotalCharsTyped, &s.TotalSessionsDuration, &s.SessionsCount,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	rows, err := q.Query("SELECT ngram, count, typed, errors, duration FROM ngrams")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ngram, duration string
+		var ns ngramStat
+		if err := rows.Scan(&ngram, &ns.Count, &ns.Typed, &ns.Errors, &duration); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(duration), &ns.Duration); err != nil {
+			return nil, fmt.Errorf("Broken stats of %#v: %w", ngram, err)
+		}
+		s.NGrams[ngram] = ns
+	}
+	return s, rows.Err()
+}
+
+func (st *sqliteStore) UpdateStats(update func(s *stats)) (*stats, error) {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := loadSQLiteStats(tx)
+	if err != nil {
+		return nil, err
+	}
+	old := s.copy()
+	update(s)
+	// session changes only few n-grams, so only they are written
+	changed := make(map[string]ngramStat)
+	for k, v := range s.NGrams {
+		if o, ok := old.NGrams[k]; !ok || o != v {
+			changed[k] = v
+		}
+	}
+	if err := saveSQLiteStats(tx, s, changed); err != nil {
+		return nil, err
+	}
+	return s, tx.Commit()
+}
+
+func (st *sqliteStore) ReplaceStats(build func() (*stats, error)) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s, err := build()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM ngrams"); err != nil {
+		return err
+	}
+	if err := saveSQLiteStats(tx, s, s.NGrams); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveSQLiteStats saves totals of s, and given n-grams
+func saveSQLiteStats(tx *sql.Tx, s *stats, ngrams map[string]ngramStat) error {
+	_, err := tx.Exec(
+		`INSERT INTO totals (id, chars, duration, sessions) VALUES (1, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET chars = excluded.chars, duration = excluded.duration, sessions = excluded.sessions`,
+		s.TotalCharsTyped, s.TotalSessionsDuration, s.SessionsCount,
+	)
+	if err != nil {
+		return err
+	}
+	upsert, err := tx.Prepare(
+		`INSERT INTO ngrams (ngram, n, count, typed, errors, duration) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (ngram) DO UPDATE SET
+			count = excluded.count, typed = excluded.typed,
+			errors = excluded.errors, duration = excluded.duration`,
+	)
+	if err != nil {
+		return err
+	}
+	defer upsert.Close()
+	for ngram, ns := range ngrams {
+		duration, err := json.Marshal(ns.Duration)
+		if err != nil {
+			return err
+		}
+		_, err = upsert.Exec(ngram, len([]rune(ngram)), ns.Count, ns.Typed, ns.Errors, string(duration))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (st *sqliteStore) Progress(filename string) (int, error) {
+	var line int
+	err := st.progressDB.QueryRow("SELECT line FROM progress WHERE file = ?", filename).Scan(&line)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return line, err
+}
+
+func (st *sqliteStore) UpdateProgress(filename string, update func(line int) int) (int, error) {
+	tx, err := st.progressDB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var line int
+	err = tx.QueryRow("SELECT line FROM progress WHERE file = ?", filename).Scan(&line)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	line = update(line)
+	_, err = tx.Exec(
+		"INSERT INTO progress (file, line) VALUES (?, ?) ON CONFLICT (file) DO UPDATE SET line = excluded.line",
+		filename, line,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return line, tx.Commit()
+}
+
+func (st *sqliteStore) Close() error {
+	if st.progressDB != st.db {
+		st.progressDB.Close()
+	}
+	return st.db.Close()
+}
+
+// ImportJSON copies sessions, their key events, stats and progress in text files
+// from JSON files to dst.
+// dst should not have any sessions yet, so they are not imported twice.
+// Returns number of imported sessions.
+func ImportJSON(dst Store) (int, error) {
+	existing, err := dst.Sessions()
+	if err != nil {
+		return 0, err
+	}
+	var e statLogEntry
+	found, err := existing.Next(&e)
+	existing.Close()
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return 0, fmt.Errorf("Store already has sessions, they will not be imported again")
+	}
+
+	src := jsonStore{}
+	s, err := src.LoadStats()
+	if err != nil {
+		return 0, err
+	}
+	if s.outdated { // durations are computed again before they are copied
+		if s, err = upgradeStats(src, s); err != nil {
+			return 0, err
+		}
+	}
+	events, err := src.allEvents()
+	if err != nil {
+		return 0, err
+	}
+	sessionsIter, err := src.Sessions()
+	if err != nil {
+		return 0, err
+	}
+	defer sessionsIter.Close()
+	count := 0
+	for {
+		var entry statLogEntry
+		cont, err := sessionsIter.Next(&entry)
+		if err != nil {
+			return count, err
+		}
+		if !cont {
+			break
+		}
+		var ev []KeyEvent
+		// events are in the same order as sessions that started in the same second
+		if queue := events[entry.Start]; len(queue) > 0 {
+			ev, events[entry.Start] = queue[0], queue[1:]
+		}
+		if err := dst.AppendSession(entry, ev); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := dst.ReplaceStats(func() (*stats, error) { return s, nil }); err != nil {
+		return count, err
+	}
+	progress, err := src.allProgress()
+	if err != nil {
+		return count, err
+	}
+	for filename, line := range progress {
+		line := line
+		if _, err := dst.UpdateProgress(filename, func(int) int { return line }); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
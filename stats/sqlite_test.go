@@ -0,0 +1,166 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestImportJSONToSQLite(t *testing.T) {
+	dir, cleanup := withTempStore(t)
+	defer cleanup()
+
+	events := []KeyEvent{{Key: "Rune", Rune: "a", Time: 0}, {Key: "Rune", Rune: "n", Typed: "b", Time: 0.3, SessionTime: 0.1, Position: 1}}
+	sessions := []Session{
+		{Text: []rune("abcdef"), Timeline: []float64{0, 0.1, 0.3, 0.6, 1.0, 1.5}, Events: events},
+		{
+			Text:     []rune("fedcba"),
+			Timeline: []float64{0, 0.2, 0.3, 0.5, 0.9, 1.2},
+			Mistakes: []Mistake{{Position: 2, Expected: "d", Typed: "s", Time: 0.25}},
+			Training: true,
+		},
+	}
+	for _, s := range sessions {
+		if err := SaveSession(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := loadedStats(t)
+	savedProgress, err := wpmProgress(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []float64{48, 60, calcWPM(3, 0.7)}; !reflect.DeepEqual(savedProgress, expected) {
+		t.Errorf("Expected progress %v, got %v", expected, savedProgress)
+	}
+	if _, err := store.UpdateProgress("text.txt", func(int) int { return 42 }); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := openSQLite(filepath.Join(dir, SQLiteFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	n, err := ImportJSON(db)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 sessions to be imported, got %d, %v", n, err)
+	}
+	if _, err := ImportJSON(db); err == nil {
+		t.Errorf("Sessions should not be imported twice")
+	}
+
+	SetStore(db)
+	imported, err := loadStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, *imported) {
+		t.Errorf("Expected imported stats %+v to be same as saved %+v", *imported, saved)
+	}
+	if progress, err := wpmProgress(time.Second); err != nil || !reflect.DeepEqual(progress, savedProgress) {
+		t.Errorf("Expected progress computed from database %v to be same as from log %v, %v", progress, savedProgress, err)
+	}
+	if line, err := store.Progress("text.txt"); err != nil || line != 42 {
+		t.Errorf("Expected progress to be imported as line 42, got %d, %v", line, err)
+	}
+	last, err := LoadSession(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(last.Mistakes, sessions[1].Mistakes) || !last.Training {
+		t.Errorf("Expected last session to be %+v, got %+v", sessions[1], last)
+	}
+	first, err := LoadSession(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both sessions started in the same second, so events should be matched by order
+	if !reflect.DeepEqual(first.Events, events) {
+		t.Errorf("Expected events %+v, got %+v", events, first.Events)
+	}
+
+	// stats updated in database should be same as rebuilt from sessions in it
+	if err := SaveSession(sessions[0]); err != nil {
+		t.Fatal(err)
+	}
+	updated := loadedStats(t)
+	if _, err := Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	SetStore(db) // to load stats from database, not from cache
+	rebuilt, err := loadStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(updated, *rebuilt) {
+		t.Errorf("Expected rebuilt stats %+v to be same as updated %+v", *rebuilt, updated)
+	}
+}
+
+func TestSQLiteProgressIsSharedByLayouts(t *testing.T) {
+	dir, cleanup := withTempStore(t)
+	defer cleanup()
+
+	native, err := OpenSQLite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = native.UpdateProgress("text.txt", func(int) int { return 42 })
+	native.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Layout = "colemak"
+	defer func() { Layout = "" }()
+	simulated, err := OpenSQLite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer simulated.Close()
+	if line, err := simulated.Progress("text.txt"); err != nil || line != 42 {
+		t.Errorf("Expected progress of native layout to be line 42, got %d, %v", line, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, layoutFile(SQLiteFile))); err != nil {
+		t.Errorf("Expected sessions of simulated layout to be kept in separate database: %v", err)
+	}
+}
+
+func TestOldJSONStatsAreUpgradedOnImport(t *testing.T) {
+	dir, cleanup := withTempStore(t)
+	defer cleanup()
+
+	old := `{"TotalCharsTyped": 5, "TotalSessionsDuration": 1, "SessionsCount": 1,
+		"Trigrams": {"abc": {"c": 1, "d": {"l": 1, "i": 1, "v": [600,0,0,0,0,0,0,0,0,0]}}}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, StatsFile), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"start": "2020-01-01T00:00:00Z", "text": "abcde", "timeline": [0, 0.1, 0.3, 0.6, 1.0]}` + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, LogStatsFile), []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := openSQLite(filepath.Join(dir, SQLiteFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := ImportJSON(db); err != nil {
+		t.Fatal(err)
+	}
+	SetStore(db)
+	s, err := loadStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.NGrams["bcd"].Duration.Average(0); got != 0.6 {
+		t.Errorf("Expected durations of old stats to be computed again, got %f", got)
+	}
+	if s.NGrams["abc"].Count != 1 || s.SessionsCount != 1 {
+		t.Errorf("Expected counts of old stats to be kept, got %+v", s)
+	}
+}
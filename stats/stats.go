@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
-	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bunyk/gokeybr/fs"
 )
@@ -20,74 +21,157 @@ const MinSessionLength = 5
 const LogStatsFile = "sessions_log.jsonl"
 const StatsFile = "stats.json"
 
-func SaveSession(start time.Time, text []rune, timeline []float64, training bool) error {
-	if len(text) != len(timeline) {
+// Layout which is practiced by simulating it on keyboard with other layout.
+// Empty when layout set in system is practiced.
+// Stats of simulated layouts are kept in separate files, to not mix with native.
+var Layout string
+
+// layoutFile returns name of file to keep stats of practiced layout in
+func layoutFile(name string) string {
+	if Layout == "" {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "_" + Layout + ext
+}
+
+// Mistake is a single wrong keystroke made during session
+type Mistake struct {
+	Position int     `json:"pos"`      // index in text of character that was expected
+	Expected string  `json:"expected"` // character that was expected
+	Typed    string  `json:"typed"`    // character that was typed instead
+	Time     float64 `json:"time"`     // seconds since start of session, same as in timeline
+}
+
+// Gap is break in typing, that was cut from timeline
+type Gap struct {
+	Position int     `json:"pos"`      // index in text of character typed after break
+	Time     float64 `json:"time"`     // second in timeline when typing resumed
+	Duration float64 `json:"duration"` // original duration of break in seconds, before it was cut
+}
+
+// Session is everything that is recorded about one typing session
+type Session struct {
+	Start    time.Time
+	Text     []rune
+	Timeline []float64
+	Mistakes []Mistake
+	Mode     string     // name of drill pass session was typed in, empty for regular sessions
+	Training bool       // whether text was generated from stats
+	Events   []KeyEvent // raw keyboard events, saved only when recorded
+	Gaps     []Gap
+}
+
+// SaveSession appends session to log and updates stats with it.
+func SaveSession(s Session) error {
+	if len(s.Text) != len(s.Timeline) {
 		return fmt.Errorf(
 			"Length of text (%d) does not match leght of timeline (%d)! Stats not saved.",
-			len(text), len(timeline),
+			len(s.Text), len(s.Timeline),
 		)
 	}
-	if len(text) < MinSessionLength {
-		fmt.Printf("Not updating stats for session only %d characters long\n", len(text))
+	if len(s.Text) < MinSessionLength {
+		fmt.Printf("Not updating stats for session only %d characters long\n", len(s.Text))
 		return nil
 	}
-	if err := fs.AppendJSONLine(
-		LogStatsFile,
+	// stats of old version are computed again from log before session is added to it
+	if _, err := loadStats(); err != nil {
+		return err
+	}
+	if err := store.AppendSession(
 		statLogEntry{
-			Start:    start.Format(time.RFC3339),
-			Text:     string(text),
-			Timeline: timeline,
+			Version:  logVersion,
+			Start:    s.Start.Format(time.RFC3339Nano), // to match events only of this session
+			Text:     string(s.Text),
+			Timeline: s.Timeline,
+			Mistakes: s.Mistakes,
+			Mode:     s.Mode,
+			Gaps:     s.Gaps,
+			Training: s.Training,
 		},
+		s.Events,
 	); err != nil {
 		return err
 	}
-	return updateStats(text, timeline, training)
+	return updateStats(s.Text, s.Timeline, s.Mistakes, s.Training)
 }
 
-func RandomTraining(length int) (string, error) {
-	trigrams, err := getTrigrams()
+// Orders of n-grams to keep stats for: 1 for keys, 2 for bigrams, 3 for trigrams...
+var Orders = []int{1, 2, 3}
+
+// DefaultOrder of n-grams used to generate training sessions
+const DefaultOrder = 3
+
+func RandomTraining(length, order int) (string, error) {
+	ngrams, err := getNGrams(order)
 	if err != nil {
 		return "", err
 	}
 	if length == 0 {
 		length = 100
 	}
-	return markovSequence(trigrams, length), nil
+	return markovSequence(ngrams, order, length), nil
 }
 
-func getTrigrams() ([]TrigramScore, error) {
+// RandomTrainingStream returns function that on each call generates next
+// length characters of random training text, continuing text generated before
+func RandomTrainingStream(length, order int) (func() string, error) {
+	ngrams, err := getNGrams(order)
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		length = 100
+	}
+	chain := buildMarkovChain(ngrams)
+	text := markovSeed(ngrams)
+	first := true
+	return func() string {
+		start := len(text)
+		if first { // seed is also part of text
+			start = 0
+			first = false
+		}
+		text = chain.continueSequence(text, order, start+length)
+		chunk := string(text[start:])
+		text = text[len(text)-order:] // only last n-gram is needed to continue
+		return chunk
+	}, nil
+}
+
+func getNGrams(order int) ([]NGramScore, error) {
+	if order < 1 {
+		return nil, fmt.Errorf("Order of n-grams should be at least 1, got %d", order)
+	}
 	stats, err := loadStats()
 	if err != nil {
 		return nil, err
 	}
 	fmt.Println("Loaded stats, generating training sequence")
 
-	trigrams := stats.trigramsToTrain()
-	if len(trigrams) < NWeakest {
+	ngrams := stats.ngramsToTrain(order)
+	if len(ngrams) < NWeakest {
 		return nil, fmt.Errorf("Not enought stats yet to generate good exercise")
 	}
-	return trigrams, err
+	return ngrams, err
 }
 
-func WeakestTraining(length int) (string, error) {
+func WeakestTraining(length, order int) (string, error) {
 	if length == 0 {
 		length = 100
 	}
-	trigrams, err := getTrigrams()
+	ngrams, err := getNGrams(order)
 	if err != nil {
 		return "", err
 	}
-	if length == 0 {
-		length = 100
-	}
-	return weakestSequence(trigrams, length), nil
+	return weakestSequence(ngrams, length), nil
 }
 
 // Typing speed we think is unreachable
 const speedOfLight = 150.0 // wpm
 
-func effortResult(trigramTime float64) float64 {
-	speed := time2wpm(trigramTime)
+func effortResult(ngramTime float64, order int) float64 {
+	speed := ngramWPM(ngramTime, order)
 	q := speed / speedOfLight
 	q = q * q
 	if q > 1.0 {
@@ -96,26 +180,29 @@ func effortResult(trigramTime float64) float64 {
 	return math.Sqrt(1.0 - q)
 }
 
-func weakestSequence(trigrams []TrigramScore, length int) string {
-	// First, we start from the weakest trigram, say abc
+func weakestSequence(ngrams []NGramScore, length int) string {
+	// First, we start from the weakest n-gram, say trigram abc
 	// Easiest - we would just repeat it, like abcabcabc..., but
 	// maybe bca is already trained good enough. So we threat each
 	// trigram abc as graph edge ab -> bc, with the weight = 1 / score of trigram
 	// And then we try to find shortest path from bc to ab.
 	// After that just repeat that path until we get sequence of required length
-	//start := trigrams[0].Trigram
-	finish, start := headTail(trigrams[0].Trigram)
+	weakest := []rune(ngrams[0].NGram)
+	if len(weakest) == 1 { // keys have no heads and tails, nothing to connect
+		return wrap(weakest, length)
+	}
+	finish, start := headTail(ngrams[0].NGram)
 
 	// Build graph
-	edges := make([]edge, 0, len(trigrams))
+	edges := make([]edge, 0, len(ngrams))
 	vertices := make(map[string]bool)
-	for _, trigram := range trigrams {
-		if trigram.Score > 0 {
-			h, t := headTail(trigram.Trigram)
+	for _, ngram := range ngrams {
+		if ngram.Score > 0 {
+			h, t := headTail(ngram.NGram)
 			edges = append(edges, edge{
 				v1: h,
 				v2: t,
-				w:  1.0 / trigram.Score,
+				w:  1.0 / ngram.Score,
 			})
 			vertices[h] = true
 			vertices[t] = true
@@ -141,7 +228,7 @@ func weakestSequence(trigrams []TrigramScore, length int) string {
 	}
 	var loop []rune
 	if len(path) == 0 {
-		loop = []rune(trigrams[0].Trigram)
+		loop = weakest
 	} else {
 		for i := len(path) - 1; i >= 0; i-- {
 			r := []rune(path[i])[0]
@@ -162,9 +249,9 @@ func wrap(loop []rune, l int) string {
 }
 
 // split abc to ab & bc (with unicode support)
-func headTail(trigram string) (string, string) {
-	r := []rune(trigram)
-	return string(r[:2]), string(r[1:])
+func headTail(ngram string) (string, string) {
+	r := []rune(ngram)
+	return string(r[:len(r)-1]), string(r[1:])
 }
 
 type edge struct {
@@ -193,54 +280,81 @@ func bellmanFord(start string, vertices map[string]bool, edges []edge) map[strin
 	return predecessor
 }
 
-func updateStats(text []rune, timeline []float64, training bool) error {
-	stats, err := loadStats()
-	if err != nil {
-		return err
-	}
-	stats.addSession(text, timeline, training)
-	return fs.SaveJSON(StatsFile, stats)
+func updateStats(text []rune, timeline []float64, mistakes []Mistake, training bool) error {
+	// Stats are loaded again, because other process could save them while session was typed
+	_, err := store.UpdateStats(func(s *stats) {
+		s.addSession(text, timeline, mistakes, training, true)
+	})
+	return err
 }
 
 type stats struct {
+	Version               int `json:"version"` // of schema
 	TotalCharsTyped       int
 	TotalSessionsDuration float64
 	SessionsCount         int
-	Trigrams              map[string]trigramStat
+	// Stats of n-grams of all orders, order of n-gram is its length
+	NGrams map[string]ngramStat
+
+	outdated bool // loaded from old version, and should be computed again from log
 }
 
 func (s stats) AverageCharDuration() float64 {
 	return s.TotalSessionsDuration / float64(s.TotalCharsTyped)
 }
 
-type trigramStat struct {
+type ngramStat struct {
 	Count    int    `json:"c"`
 	Duration Window `json:"d"`
+	Typed    int    `json:"t,omitempty"` // how many times n-gram was typed, including training sessions
+	Errors   int    `json:"e,omitempty"` // how many wrong keys were hit while typing last character of n-gram
+}
+
+// ErrorRate returns average number of mistakes made per typing of n-gram
+func (ns ngramStat) ErrorRate() float64 {
+	if ns.Typed == 0 {
+		return 0
+	}
+	return float64(ns.Errors) / float64(ns.Typed)
 }
 
-// Score approximates time that will be spent typing this trigram
-// It is total frequency of trigram (it's count)
-// multiplied by current average duration of typing one
-func (ts trigramStat) Score(avgDuration float64) float64 {
-	duration := ts.Duration.Average(avgDuration)
-	return float64(ts.Count) * effortResult(duration)
+// Score approximates time that will be spent typing this n-gram
+// It is total frequency of n-gram (it's count)
+// multiplied by current average duration of typing one.
+// Each mistake is considered to cost as much time as typing n-gram again,
+// because it needs to be noticed and corrected.
+func (ns ngramStat) Score(avgDuration float64, order int) float64 {
+	duration := ns.Duration.Average(avgDuration) * (1.0 + ns.ErrorRate())
+	return float64(ns.Count) * effortResult(duration, order)
 }
 
-type TrigramScore struct {
-	Trigram string
-	Score   float64
+type NGramScore struct {
+	NGram string
+	Score float64
 }
 
-// return list of trigrams with their relative importance to train
-// the more frequent is trigram and the more long it takes to type it
+// ngrams returns stats of n-grams of given order
+func (s stats) ngrams(order int) map[string]ngramStat {
+	res := make(map[string]ngramStat)
+	for k, ns := range s.NGrams {
+		if utf8.RuneCountInString(k) == order {
+			res[k] = ns
+		}
+	}
+	return res
+}
+
+// return list of n-grams with their relative importance to train
+// the more frequent is n-gram and the more long it takes to type it
 // the more important will it be to train it
-func (s stats) trigramsToTrain() []TrigramScore {
-	res := make([]TrigramScore, 0, len(s.Trigrams))
-	for t, ts := range s.Trigrams {
-		sc := ts.Score(s.AverageCharDuration() * 3)
-		res = append(res, TrigramScore{
-			Trigram: t,
-			Score:   sc,
+func (s stats) ngramsToTrain(order int) []NGramScore {
+	ngrams := s.ngrams(order)
+	res := make([]NGramScore, 0, len(ngrams))
+	for k, ns := range ngrams {
+		sc := ns.Score(s.AverageCharDuration()*float64(order), order)
+		res = append(res, NGramScore{
+			NGram: k,
+			Score: sc,
 		})
 	}
 	sort.Slice(res, func(i, j int) bool {
@@ -249,23 +363,55 @@ func (s stats) trigramsToTrain() []TrigramScore {
 	return res
 }
 
+// return list of n-grams of given order that had at least one mistake,
+// sorted from the one with highest error rate
+func (s stats) mostMistyped(order int) []string {
+	ngrams := s.ngrams(order)
+	res := make([]string, 0)
+	for k, ns := range ngrams {
+		if ns.Errors > 0 {
+			res = append(res, k)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		ri, rj := ngrams[res[i]].ErrorRate(), ngrams[res[j]].ErrorRate()
+		if ri == rj {
+			return ngrams[res[i]].Errors > ngrams[res[j]].Errors
+		}
+		return ri > rj
+	})
+	return res
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 type markovChain map[string]map[rune]float64
 
 const NWeakest = 10
 
-func markovSequence(trigrams []TrigramScore, length int) string {
+func markovSequence(ngrams []NGramScore, order, length int) string {
+	chain := buildMarkovChain(ngrams)
+	return string(chain.continueSequence(markovSeed(ngrams), order, length))
+}
+
+// buildMarkovChain makes chain, where next character depends on previous order - 1 characters
+func buildMarkovChain(ngrams []NGramScore) markovChain {
 	chain := make(markovChain)
-	// build Markov chain
-	for _, ts := range trigrams {
-		t := []rune(ts.Trigram)
-		bigram := string(t[:2])
-		if ts.Score == 0 {
-			ts.Score = 0.00000001
+	for _, ns := range ngrams {
+		t := []rune(ns.NGram)
+		head := string(t[:len(t)-1])
+		if ns.Score == 0 {
+			ns.Score = 0.00000001
 		}
-		if chain[bigram] == nil {
-			chain[bigram] = make(map[rune]float64)
+		if chain[head] == nil {
+			chain[head] = make(map[rune]float64)
 		}
-		chain[bigram][t[2]] = ts.Score
+		chain[head][t[len(t)-1]] = ns.Score
 	}
 	// normalize Markov chain
 	for _, links := range chain {
@@ -277,14 +423,20 @@ func markovSequence(trigrams []TrigramScore, length int) string {
 			links[k] = ls / totalScore
 		}
 	}
-	text := make([]rune, 0, length)
-	for _, r := range trigrams[rand.Intn(NWeakest)].Trigram {
-		text = append(text, r)
-	}
+	return chain
+}
+
+// markovSeed returns one of the weakest n-grams to start sequence from
+func markovSeed(ngrams []NGramScore) []rune {
+	return []rune(ngrams[rand.Intn(NWeakest)].NGram)
+}
+
+// continueSequence appends characters to text until it reaches length
+func (chain markovChain) continueSequence(text []rune, order, length int) []rune {
 	for len(text) < length {
-		links := chain[string(text[len(text)-2:])]
+		links := chain[string(text[len(text)-order+1:])]
 		if len(links) == 0 {
-			text = append(text, text[len(text)%3])
+			text = append(text, text[len(text)%order])
 		}
 		choice := rand.Float64()
 		totalScore := 0.0
@@ -296,51 +448,218 @@ func markovSequence(trigrams []TrigramScore, length int) string {
 			}
 		}
 	}
-	return string(text)
+	return text
+}
+
+// addLogEntry counts session from log. Returns false when it is broken.
+// Only totals are counted for imported sessions without text.
+func (s *stats) addLogEntry(e statLogEntry) bool {
+	if e.broken() {
+		return false
+	}
+	if e.Text == "" {
+		s.addTotals(e.totals())
+	} else {
+		s.addSession([]rune(e.Text), e.Timeline, e.Mistakes, e.Training, e.timed())
+	}
+	return true
 }
 
-func (s *stats) addSession(text []rune, timeline []float64, training bool) {
+func (s *stats) addTotals(chars int, duration float64) {
 	s.SessionsCount++
-	s.TotalCharsTyped += len(text)
-	s.TotalSessionsDuration += timeline[len(timeline)-1]
-	for i := 0; i < len(text)-3; i++ {
-		k := string(text[i : i+3])
-		tr := s.Trigrams[k]
-		if !training { // we do not count trigram frequencies in training sessions
-			tr.Count++ // because that will make them stuck in training longer
+	s.TotalCharsTyped += chars
+	s.TotalSessionsDuration += duration
+}
+
+// addSession counts session in stats. When timeline is not timed, but synthesized
+// from speed of imported session, it is counted only in totals, not in durations of n-grams.
+func (s *stats) addSession(text []rune, timeline []float64, mistakes []Mistake, training, timed bool) {
+	s.addTotals(len(text), timeline[len(timeline)-1])
+	for _, n := range Orders {
+		first := 1 // of n-grams which are counted in errors
+		if n == 1 && len(text) > 0 {
+			// key of first character is counted only in typed and errors, as it has no time
+			first = 0
+			ns := s.NGrams[string(text[:1])]
+			ns.Typed++
+			s.NGrams[string(text[:1])] = ns
+		}
+		// n-gram starting at i is typed from moment previous character was typed,
+		// till moment its last character is typed, so first character has no n-grams
+		for i := 1; i+n <= len(text); i++ {
+			k := string(text[i : i+n])
+			ns := s.NGrams[k]
+			if !training { // we do not count n-gram frequencies in training sessions
+				ns.Count++ // because that will make them stuck in training longer
+			}
+			ns.Typed++
+			if timed {
+				ns.Duration.Append(timeline[i+n-1] - timeline[i-1])
+			}
+			s.NGrams[k] = ns
+		}
+		for _, m := range mistakes {
+			i := m.Position - n + 1 // mistake is attributed to n-gram ending on expected character
+			if i < first || m.Position >= len(text) {
+				continue // n-gram was not counted above
+			}
+			k := string(text[i : i+n])
+			ns := s.NGrams[k]
+			ns.Errors++
+			s.NGrams[k] = ns
 		}
-		tr.Duration.Append(timeline[i+3] - timeline[i])
-		s.Trigrams[k] = tr
 	}
 }
 
-var statsCache *stats
-
 func loadStats() (*stats, error) {
-	if statsCache != nil {
-		return statsCache, nil
+	s, err := store.LoadStats()
+	if err != nil || !s.outdated {
+		return s, err
+	}
+	return upgradeStats(store, s)
+}
+
+// upgradeStats computes durations of n-grams in stats saved by older version in st again
+// from its log of sessions, as they were measured differently. Counts and totals are kept, and log should
+// have all sessions counted in them, otherwise durations of other sessions would be lost.
+func upgradeStats(st Store, old *stats) (*stats, error) {
+	fromLog := newStats()
+	sessionsIter, err := st.Sessions()
+	if err != nil {
+		return nil, err
+	}
+	defer sessionsIter.Close()
+	sessions := 0
+	for {
+		var logEntry statLogEntry
+		cont, err := sessionsIter.Next(&logEntry)
+		if err != nil {
+			return nil, err
+		}
+		if !cont {
+			break
+		}
+		if fromLog.addLogEntry(logEntry) {
+			sessions++
+		}
 	}
-	statsCache = &stats{Trigrams: make(map[string]trigramStat)}
-	err := fs.LoadJSON(StatsFile, statsCache)
+	if sessions < old.SessionsCount || fromLog.TotalCharsTyped < old.TotalCharsTyped {
+		return nil, fmt.Errorf(
+			"%s was saved by older version of gokeybr, and durations of n-grams should be computed again "+
+				"from log of sessions, but it has only %d of %d sessions. Run \"gokeybr stats rebuild\" "+
+				"to compute stats only from sessions in log",
+			layoutFile(StatsFile), sessions, old.SessionsCount,
+		)
+	}
+	backup, err := fs.BackupFile(layoutFile(StatsFile))
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Printf("Warning: File %s does not exist! It will be created.\n", StatsFile)
-			return statsCache, nil
+		return nil, err
+	}
+	fmt.Println("Stats were saved by older version of gokeybr, computing durations of n-grams again from log of sessions")
+	if backup != "" {
+		fmt.Println("Old stats are saved to", backup)
+	}
+	s := old.copy()
+	s.outdated = false
+	for k, ns := range fromLog.NGrams {
+		if migrated, ok := s.NGrams[k]; ok {
+			migrated.Duration = ns.Duration
+			ns = migrated
 		}
+		s.NGrams[k] = ns // n-grams of orders that were not counted before are known only from log
+	}
+	if err := st.ReplaceStats(func() (*stats, error) { return s, nil }); err != nil {
 		return nil, err
 	}
-	return statsCache, nil
+	return st.LoadStats()
+}
+
+func newStats() *stats {
+	return &stats{Version: statsVersion, NGrams: make(map[string]ngramStat)}
+}
+
+// copy returns stats that could be changed without changing s
+func (s stats) copy() *stats {
+	ngrams := make(map[string]ngramStat, len(s.NGrams))
+	for k, v := range s.NGrams {
+		ngrams[k] = v
+	}
+	s.NGrams = ngrams
+	return &s
+}
+
+// Rebuild computes stats again from log of all sessions, and replaces saved ones.
+// Returns number of sessions in log.
+func Rebuild() (int, error) {
+	s := newStats()
+	sessions := 0
+	err := store.ReplaceStats(func() (*stats, error) {
+		sessionsIter, err := store.Sessions()
+		if err != nil {
+			return nil, err
+		}
+		defer sessionsIter.Close()
+		for {
+			var logEntry statLogEntry
+			cont, err := sessionsIter.Next(&logEntry)
+			if err != nil {
+				return nil, err
+			}
+			if !cont {
+				return s, nil
+			}
+			if s.addLogEntry(logEntry) {
+				sessions++
+			}
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sessions, nil
 }
 
 type statLogEntry struct {
+	Version  int       `json:"version"` // of schema
 	Start    string    `json:"start"`
 	Text     string    `json:"text"`
 	Timeline []float64 `json:"timeline"`
+	Mistakes []Mistake `json:"mistakes,omitempty"`
+	Mode     string    `json:"mode,omitempty"`
+	Gaps     []Gap     `json:"gaps,omitempty"`
+	Training bool      `json:"training,omitempty"` // session was generated from stats
+	// Sessions imported from other programs
+	Source   string  `json:"source,omitempty"`   // name of program
+	Chars    int     `json:"chars,omitempty"`    // only when there is no timeline
+	Duration float64 `json:"duration,omitempty"` // in seconds, only when there is no timeline
+	Errors   int     `json:"errors,omitempty"`   // number of mistakes, when their positions are not known
 }
 
-const wpmPer1secTrigramTime = 36.0 // 3 / 5 * 60
-func time2wpm(t float64) float64 {
-	return wpmPer1secTrigramTime / t
+// broken returns whether entry could not be counted in stats
+func (e statLogEntry) broken() bool {
+	if e.Text == "" && len(e.Timeline) == 0 {
+		return e.Chars <= 0 || e.Duration <= 0
+	}
+	return e.Text != "" && len([]rune(e.Text)) != len(e.Timeline)
+}
+
+// timed returns whether timeline was measured while typing, and not synthesized
+// from speed of session imported from other program
+func (e statLogEntry) timed() bool {
+	return e.Source == ""
+}
+
+// totals returns number of characters and seconds it took to type them
+func (e statLogEntry) totals() (chars int, duration float64) {
+	if len(e.Timeline) == 0 {
+		return e.Chars, e.Duration
+	}
+	return len(e.Timeline), e.Timeline[len(e.Timeline)-1]
+}
+
+// ngramWPM converts time of typing n-gram of given order to speed
+func ngramWPM(t float64, order int) float64 {
+	return calcWPM(order, t)
 }
 
 func AverageWPM() float64 {
@@ -348,8 +667,7 @@ func AverageWPM() float64 {
 	if err != nil { // If stats loaded to fail
 		return 50.0 // return world average
 	}
-	avDur := stats.AverageCharDuration() * 3.0
-	return time2wpm(avDur)
+	return calcWPM(1, stats.AverageCharDuration())
 }
 
 func GetReport() (string, error) {
@@ -365,41 +683,8 @@ func GetReport() (string, error) {
 	print("Total time in training: %s\n", time.Second*time.Duration(stats.TotalSessionsDuration))
 	print("Average typing speed: %.1f wpm\n", AverageWPM())
 	print("Training sessions: %d\n", stats.SessionsCount)
-	var fastestTr, slowestTr string
-	fastestTime := 10.0
-	slowestTime := 0.0
-	for t, s := range stats.Trigrams {
-		dur := s.Duration.Average(0)
-		if dur < fastestTime {
-			fastestTime = dur
-			fastestTr = t
-		}
-		if dur > slowestTime {
-			slowestTime = dur
-			slowestTr = t
-		}
-	}
-	print("\nTrigram stats:\n")
-	print("Slowest: %#v %4.2fs (%.1f wpm)\n", slowestTr, slowestTime, time2wpm(slowestTime))
-	print("Fastest: %#v %4.2fs (%.1f wpm)\n", fastestTr, fastestTime, time2wpm(fastestTime))
-
-	trigrams := stats.trigramsToTrain()
-	if len(trigrams) > 0 {
-		print("\nNeed to be trained most:\n")
-		print("Trigram |   Score | Frequency | Typing time\n")
-		for _, t := range trigrams[:20] {
-			d := stats.Trigrams[t.Trigram]
-			tr := fmt.Sprintf("%#v", t.Trigram)
-			dur := d.Duration.Average(0)
-			print(
-				"%7s | %7.2f | %9d | %4.2fs (%.1f wpm)\n",
-				tr, t.Score/stats.TotalSessionsDuration*1000.0, d.Count, dur, time2wpm(dur),
-			)
-			// we divide score to total session duration go get score approximated in promille
-			// if trigram will be the only one we type - it will have 1000 score,
-			// if it's current typing speed equals to total, average, or less if it is typed faster.
-			// if it is typed slower - score will be greater than 1000
-		}
+	for _, n := range Orders {
+		stats.reportOrder(print, n)
 	}
 	if stats.TotalSessionsDuration < 600 { // Less than 10 minutes of training, not much to show
 		print("\nTrain more to get some progress!")
@@ -424,6 +709,78 @@ func GetReport() (string, error) {
 	return strings.Join(res, ""), nil
 }
 
+// orderName returns how n-grams of given order are called
+func orderName(order int) string {
+	switch order {
+	case 1:
+		return "Key"
+	case 2:
+		return "Bigram"
+	case 3:
+		return "Trigram"
+	case 4:
+		return "Tetragram"
+	}
+	return fmt.Sprintf("%d-gram", order)
+}
+
+// reportOrder prints stats for n-grams of given order
+func (s stats) reportOrder(print func(string, ...interface{}), order int) {
+	ngrams := s.ngrams(order)
+	if len(ngrams) == 0 {
+		return
+	}
+	name := orderName(order)
+	var fastest, slowest string
+	fastestTime := 10.0 * float64(order)
+	slowestTime := 0.0
+	for k, ns := range ngrams {
+		if ns.Duration.Length == 0 {
+			continue // typed only in imported sessions, so time is not known
+		}
+		dur := ns.Duration.Average(0)
+		if dur < fastestTime {
+			fastestTime = dur
+			fastest = k
+		}
+		if dur > slowestTime {
+			slowestTime = dur
+			slowest = k
+		}
+	}
+	print("\n%s stats:\n", name)
+	print("Slowest: %#v %4.2fs (%.1f wpm)\n", slowest, slowestTime, ngramWPM(slowestTime, order))
+	print("Fastest: %#v %4.2fs (%.1f wpm)\n", fastest, fastestTime, ngramWPM(fastestTime, order))
+
+	width := len(name) // so that columns are aligned with header
+	mistyped := s.mostMistyped(order)
+	if len(mistyped) > 0 {
+		print("\nMost mistyped:\n")
+		print("%s | Errors | Typed | Error rate\n", name)
+		for _, k := range mistyped[:min(10, len(mistyped))] {
+			ns := ngrams[k]
+			print("%*s | %6d | %5d | %5.1f%%\n", width, fmt.Sprintf("%#v", k), ns.Errors, ns.Typed, ns.ErrorRate()*100.0)
+		}
+	}
+
+	toTrain := s.ngramsToTrain(order)
+	print("\nNeed to be trained most:\n")
+	print("%s |   Score | Frequency | Typing time        | Errors\n", name)
+	for _, t := range toTrain[:min(20, len(toTrain))] {
+		ns := ngrams[t.NGram]
+		dur := ns.Duration.Average(0)
+		print(
+			"%*s | %7.2f | %9d | %4.2fs (%5.1f wpm) | %5.1f%%\n",
+			width, fmt.Sprintf("%#v", t.NGram), t.Score/s.TotalSessionsDuration*1000.0,
+			ns.Count, dur, ngramWPM(dur, order), ns.ErrorRate()*100.0,
+		)
+		// we divide score to total session duration go get score approximated in promille
+		// if n-gram will be the only one we type - it will have 1000 score,
+		// if it's current typing speed equals to total, average, or less if it is typed faster.
+		// if it is typed slower - score will be greater than 1000
+	}
+}
+
 func formatDuration(d time.Duration) string {
 	h := int(d.Hours())
 	m := int(d.Minutes())
@@ -442,27 +799,14 @@ func calcWPM(chars int, seconds float64) float64 {
 	return float64(chars) / seconds * WPMinCPS
 }
 
+// wpmProgress returns average speed in each interval of training time
 func wpmProgress(intervalSize time.Duration) ([]float64, error) {
-	logStatsIter, err := fs.NewJSONLinesIterator(LogStatsFile)
-	if err != nil {
-		return nil, err
-	}
-	defer logStatsIter.Close()
-
-	var logEntry statLogEntry
 	iSec := intervalSize.Seconds()
 	var countedSeconds float64
 	var countedChars int
 	var res []float64
-	for {
-		cont, err := logStatsIter.UnmarshalNextLine(&logEntry)
-		if err != nil {
-			return nil, err
-		}
-		if !cont {
-			break
-		}
-		for i, t := range logEntry.Timeline {
+	err := store.SessionTimelines(func(timeline []float64) {
+		for i, t := range timeline {
 			if t-countedSeconds >= iSec { // Counted approximately for interval
 				res = append(res, calcWPM(i-countedChars, t-countedSeconds))
 				countedSeconds = t
@@ -470,8 +814,11 @@ func wpmProgress(intervalSize time.Duration) ([]float64, error) {
 			}
 		}
 		// compute counting debt
-		countedSeconds = countedSeconds - logEntry.Timeline[len(logEntry.Timeline)-1]
-		countedChars = countedChars - len(logEntry.Timeline)
+		countedSeconds = countedSeconds - timeline[len(timeline)-1]
+		countedChars = countedChars - len(timeline)
+	})
+	if err != nil {
+		return nil, err
 	}
 	res = append(res, calcWPM(-countedChars, -countedSeconds))
 	return res, nil
@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bunyk/gokeybr/fs"
+)
+
+func TestAddSessionNGrams(t *testing.T) {
+	s := stats{NGrams: make(map[string]ngramStat)}
+	mistakes := []Mistake{{Position: 2, Expected: "c", Typed: "x"}, {Position: 0, Expected: "a", Typed: "s"}}
+	s.addSession([]rune("abcd"), []float64{0, 0.1, 0.3, 0.6}, mistakes, false, true)
+
+	expected := map[string]float64{
+		"b": 0.1, "c": 0.2, "d": 0.3,
+		"bc": 0.3, "cd": 0.5,
+		"bcd": 0.6,
+	}
+	for k, dur := range expected {
+		got := s.NGrams[k].Duration.Average(0)
+		if diff := got - dur; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Expected %#v to take %.1fs, got %.1fs", k, dur, got)
+		}
+	}
+	if len(s.NGrams) != len(expected)+1 {
+		t.Errorf("Expected n-grams %v and key of first character, got %v", expected, s.NGrams)
+	}
+	if a := s.NGrams["a"]; a.Typed != 1 || a.Errors != 1 || a.Duration.Length != 0 {
+		t.Errorf("Expected mistake on first character to be counted for its key without time, got %+v", a)
+	}
+	for _, k := range []string{"c", "bc"} {
+		if s.NGrams[k].Errors != 1 {
+			t.Errorf("Expected mistake to be counted for %#v, got %+v", k, s.NGrams[k])
+		}
+	}
+	if s.NGrams["bcd"].Errors != 0 {
+		t.Errorf("Mistake should not be counted for n-gram not ending on it")
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	_, cleanup := withTempStore(t)
+	defer cleanup()
+
+	timeline := []float64{0, 0.1, 0.3, 0.6, 1.0, 1.5}
+	for _, training := range []bool{false, true} {
+		if err := SaveSession(Session{Text: []rune("abcdef"), Timeline: timeline, Training: training}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := loadedStats(t)
+	_ = fs.SaveJSON(StatsFile, "broken")
+
+	n, err := Rebuild()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected stats to be rebuilt from 2 sessions, got %d, %v", n, err)
+	}
+	SetStore(NewJSONStore()) // to load stats from file, not from cache
+	rebuilt, err := loadStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, *rebuilt) {
+		t.Errorf("Expected rebuilt stats %+v to be same as saved %+v", *rebuilt, saved)
+	}
+	if rebuilt.NGrams["bc"].Count != 1 {
+		t.Errorf("Training session should not be counted in frequencies, got %+v", rebuilt.NGrams["bc"])
+	}
+}
+
+// loadedStats returns copy of current stats, that will not change when they are updated
+func loadedStats(t *testing.T) stats {
+	s, err := loadStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *s.copy()
+}
+
+func TestEventsOfSessionsStartedInSameSecond(t *testing.T) {
+	dir, cleanup := withTempStore(t)
+	defer cleanup()
+	db, err := openSQLite(filepath.Join(dir, SQLiteFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	events := []KeyEvent{{Key: "Rune", Rune: "a"}, {Key: "Rune", Rune: "b", Time: 0.1, SessionTime: 0.1, Position: 1}}
+	start := time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)
+	for name, st := range map[string]Store{"json": NewJSONStore(), "sqlite": db} {
+		SetStore(st)
+		// only second session recorded events
+		for i, ev := range [][]KeyEvent{nil, events} {
+			s := Session{Start: start.Add(time.Duration(i+1) * 100 * time.Millisecond), Text: []rune("abcde"), Timeline: []float64{0, 0.1, 0.2, 0.3, 0.4}, Events: ev}
+			if err := SaveSession(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		first, err := LoadSession(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Events != nil {
+			t.Errorf("%s: expected no events in first session, got %+v", name, first.Events)
+		}
+		last, err := LoadSession(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(last.Events, events) {
+			t.Errorf("%s: expected events %+v in second session, got %+v", name, events, last.Events)
+		}
+	}
+}
+
+// withTempStore keeps files of test in temporary data directory, in JSON store.
+// Returned cleanup removes the directory and restores defaults.
+func withTempStore(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "gokeybr-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.DataDir = dir
+	SetStore(NewJSONStore())
+	return dir, func() {
+		SetStore(NewJSONStore())
+		fs.DataDir = ""
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,335 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bunyk/gokeybr/fs"
+)
+
+// Store keeps log of sessions, and stats aggregated from it
+type Store interface {
+	// AppendSession adds session to log, with its key events, when they were recorded
+	AppendSession(e statLogEntry, events []KeyEvent) error
+	// InsertSessions adds sessions without key events to log, at places by their start,
+	// as they could be older than sessions already in it
+	InsertSessions(entries []statLogEntry) error
+	// Sessions iterates over log of sessions, from the oldest one
+	Sessions() (sessionIterator, error)
+	// SessionTimelines calls f with timeline of each session that has it, from the oldest one.
+	// Is faster than Sessions when texts and mistakes are not needed.
+	SessionTimelines(f func(timeline []float64)) error
+	// Events returns key events of session that started at start,
+	// skip is number of sessions that started at the same time before it,
+	// which is possible only for sessions saved with start in seconds by older versions
+	Events(start string, skip int) ([]KeyEvent, error)
+	// LoadStats returns aggregated stats, empty if there are none yet
+	LoadStats() (*stats, error)
+	// UpdateStats loads stats, changes them by update and saves,
+	// while other processes could not change them. Returns updated stats.
+	UpdateStats(update func(s *stats)) (*stats, error)
+	// ReplaceStats saves stats returned by build, even if saved ones are broken
+	ReplaceStats(build func() (*stats, error)) error
+	// Progress returns line of text file, from which typing should continue
+	Progress(filename string) (int, error)
+	// UpdateProgress changes line of text file by update, and returns new one
+	UpdateProgress(filename string, update func(line int) int) (int, error)
+	Close() error
+}
+
+type sessionIterator interface {
+	// Next loads next session into e, returns false when there are no more
+	Next(e *statLogEntry) (bool, error)
+	Close()
+}
+
+var store Store = &cachedStore{Store: NewJSONStore()}
+
+// SetStore changes where sessions and stats are kept. JSON files are used by default.
+func SetStore(s Store) {
+	store = &cachedStore{Store: s}
+}
+
+// cachedStore keeps stats in memory after they are loaded,
+// as they are needed on each redraw of screen
+type cachedStore struct {
+	Store
+	stats *stats
+}
+
+func (c *cachedStore) LoadStats() (*stats, error) {
+	if c.stats != nil {
+		return c.stats, nil
+	}
+	s, err := c.Store.LoadStats()
+	if err != nil {
+		return nil, err
+	}
+	c.stats = s
+	return s, nil
+}
+
+func (c *cachedStore) UpdateStats(update func(s *stats)) (*stats, error) {
+	s, err := c.Store.UpdateStats(update)
+	if err != nil {
+		return nil, err
+	}
+	c.stats = s
+	return s, nil
+}
+
+func (c *cachedStore) ReplaceStats(build func() (*stats, error)) error {
+	var built *stats
+	err := c.Store.ReplaceStats(func() (*stats, error) {
+		s, err := build()
+		built = s
+		return s, err
+	})
+	if err != nil {
+		return err
+	}
+	c.stats = built
+	return nil
+}
+
+// NewJSONStore returns store that keeps data in JSON files, like it always did:
+// sessions, events and stats in data directory of profile, progress in state directory.
+func NewJSONStore() Store {
+	return jsonStore{}
+}
+
+// jsonStore keeps sessions log, events log and stats in JSON files in data directory of profile
+type jsonStore struct{}
+
+func (jsonStore) AppendSession(e statLogEntry, events []KeyEvent) error {
+	if err := fs.AppendJSONLine(layoutFile(LogStatsFile), e); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return fs.AppendJSONLine(
+		layoutFile(EventsLogFile),
+		eventsLogEntry{Start: e.Start, Events: events},
+	)
+}
+
+// InsertSessions rewrites log with entries at places by their start. Sessions are imported
+// rarely, while log is read line by line by each command, and sorting it when reading
+// would need to load it whole into memory every time.
+func (jsonStore) InsertSessions(entries []statLogEntry) error {
+	return fs.RewriteJSONLines(layoutFile(LogStatsFile), func(lines [][]byte) ([][]byte, error) {
+		for _, e := range entries {
+			line, err := json.Marshal(e)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+		}
+		order := orderByStart(len(lines), func(i int) (time.Time, bool) {
+			var e struct {
+				Start string `json:"start"`
+			}
+			if json.Unmarshal(lines[i], &e) != nil {
+				return time.Time{}, false
+			}
+			t, err := time.Parse(time.RFC3339, e.Start)
+			return t, err == nil
+		})
+		sorted := make([][]byte, len(lines))
+		for i, j := range order {
+			sorted[i] = lines[j]
+		}
+		return sorted, nil
+	})
+}
+
+// orderByStart returns indexes of n sessions, ordered by their start.
+// Sessions that started at the same time keep their order, and ones
+// which start could not be parsed stay after previous session.
+func orderByStart(n int, start func(i int) (time.Time, bool)) []int {
+	starts := make([]time.Time, n)
+	order := make([]int, n)
+	for i := range starts {
+		order[i] = i
+		if t, ok := start(i); ok {
+			starts[i] = t
+		} else if i > 0 {
+			starts[i] = starts[i-1]
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return starts[order[a]].Before(starts[order[b]])
+	})
+	return order
+}
+
+func (jsonStore) Sessions() (sessionIterator, error) {
+	it, err := fs.NewJSONLinesIterator(layoutFile(LogStatsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return noSessions{}, nil
+		}
+		return nil, err
+	}
+	return jsonSessions{it}, nil
+}
+
+func (st jsonStore) SessionTimelines(f func(timeline []float64)) error {
+	return sessionTimelines(st, f)
+}
+
+// sessionTimelines reads timelines of sessions from log, for stores that do not keep them separately
+func sessionTimelines(st Store, f func(timeline []float64)) error {
+	sessionsIter, err := st.Sessions()
+	if err != nil {
+		return err
+	}
+	defer sessionsIter.Close()
+	for {
+		var e statLogEntry
+		cont, err := sessionsIter.Next(&e)
+		if err != nil || !cont {
+			return err
+		}
+		if len(e.Timeline) > 0 { // imported sessions with totals only have none
+			f(e.Timeline)
+		}
+	}
+}
+
+type jsonSessions struct {
+	*fs.JSONLinesIterator
+}
+
+func (s jsonSessions) Next(e *statLogEntry) (bool, error) {
+	return s.UnmarshalNextLine(e)
+}
+
+type noSessions struct{}
+
+func (noSessions) Next(e *statLogEntry) (bool, error) {
+	return false, nil
+}
+
+func (noSessions) Close() {}
+
+func (jsonStore) Events(start string, skip int) ([]KeyEvent, error) {
+	eventsIter, err := fs.NewJSONLinesIterator(layoutFile(EventsLogFile))
+	if err != nil {
+		if os.IsNotExist(err) { // events were never recorded
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer eventsIter.Close()
+
+	for {
+		var entry eventsLogEntry
+		cont, err := eventsIter.UnmarshalNextLine(&entry)
+		if err != nil || !cont {
+			return nil, err
+		}
+		if entry.Start != start {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		return entry.Events, nil
+	}
+}
+
+// allEvents returns recorded events of all sessions, grouped by start of session
+func (jsonStore) allEvents() (map[string][][]KeyEvent, error) {
+	events := make(map[string][][]KeyEvent)
+	eventsIter, err := fs.NewJSONLinesIterator(layoutFile(EventsLogFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return events, nil
+		}
+		return nil, err
+	}
+	defer eventsIter.Close()
+	for {
+		var entry eventsLogEntry
+		cont, err := eventsIter.UnmarshalNextLine(&entry)
+		if err != nil || !cont {
+			return events, err
+		}
+		events[entry.Start] = append(events[entry.Start], entry.Events)
+	}
+}
+
+func (jsonStore) LoadStats() (*stats, error) {
+	s := newStats()
+	err := fs.LoadJSON(layoutFile(StatsFile), s)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("Warning: File %s does not exist! It will be created.\n", layoutFile(StatsFile))
+			return s, nil
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+func (jsonStore) UpdateStats(update func(s *stats)) (*stats, error) {
+	s := newStats()
+	err := fs.UpdateJSON(layoutFile(StatsFile), s, func() error {
+		update(s)
+		return nil
+	})
+	return s, err
+}
+
+func (jsonStore) ReplaceStats(build func() (*stats, error)) error {
+	return fs.ReplaceJSON(layoutFile(StatsFile), func() (interface{}, error) {
+		return build()
+	})
+}
+
+// ProgressFile keeps line of each text file, from which typing should continue
+const ProgressFile = "progress.json"
+
+func (jsonStore) Progress(filename string) (int, error) {
+	var progressTable map[string]int
+	if err := fs.LoadStateJSON(ProgressFile, &progressTable); err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return progressTable[filename], nil
+}
+
+func (jsonStore) UpdateProgress(filename string, update func(line int) int) (int, error) {
+	var progressTable map[string]int
+	err := fs.UpdateStateJSON(ProgressFile, &progressTable, func() error {
+		if progressTable == nil {
+			fmt.Printf("%s is not found, will be created\n", ProgressFile)
+			progressTable = make(map[string]int)
+		}
+		progressTable[filename] = update(progressTable[filename])
+		return nil
+	})
+	return progressTable[filename], err
+}
+
+// allProgress returns lines of all files, where typing should continue
+func (jsonStore) allProgress() (map[string]int, error) {
+	var progressTable map[string]int
+	err := fs.LoadStateJSON(ProgressFile, &progressTable)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return progressTable, err
+}
+
+func (jsonStore) Close() error {
+	return nil
+}
@@ -14,7 +14,12 @@ type Window struct {
 const MillisecondsInSecond = 1000.0
 
 func (w *Window) Append(val float64) {
-	w.Values[w.Index] = int(math.Round(val * 1000.0))
+	w.appendValue(int(math.Round(val * 1000.0)))
+}
+
+// appendValue adds value in milliseconds
+func (w *Window) appendValue(ms int) {
+	w.Values[w.Index] = ms
 	w.Index = (w.Index + 1) % Capacity
 	if w.Length < Capacity {
 		w.Length++
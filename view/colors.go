@@ -0,0 +1,42 @@
+package view
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// colors that could be changed, and how to change them
+var colorSetters = map[string]func(tcell.Color){
+	"done":     func(c tcell.Color) { doneStyle = doneStyle.Foreground(c) },
+	"error":    func(c tcell.Color) { errorStyle = errorStyle.Background(c) },
+	"ghost":    func(c tcell.Color) { ghostStyle = ghostStyle.Background(c) },
+	"life":     func(c tcell.Color) { lifeStyle = lifeStyle.Foreground(c) },
+	"next-key": func(c tcell.Color) { nextKeyStyle = nextKeyStyle.Background(c) },
+}
+
+// ColorNames returns names of colors that could be set
+func ColorNames() []string {
+	res := make([]string, 0, len(colorSetters))
+	for name := range colorSetters {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// SetColor changes color of some element, color is given by name like "green" or hex value like "#00ff00"
+func SetColor(element, color string) error {
+	set, ok := colorSetters[element]
+	if !ok {
+		return fmt.Errorf("Unknown color %s, should be one of %s", element, strings.Join(ColorNames(), ", "))
+	}
+	c := tcell.GetColor(color)
+	if c == tcell.ColorDefault && color != "default" {
+		return fmt.Errorf("Unknown color value %s", color)
+	}
+	set(c)
+	return nil
+}
@@ -0,0 +1,67 @@
+package view
+
+import (
+	"github.com/bunyk/gokeybr/layout"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Keyboard is drawn under text, to not look at real one
+type Keyboard struct {
+	Layout *layout.Layout
+	Heat   map[layout.Position]float64 // from 0 for best keys to 1 for worst
+	Next   rune                        // key to highlight, 0 for none
+}
+
+const keyboardHeight = layout.SpaceRow + 1
+
+const keyWidth = 4 // with space between keys
+
+// shift of each row to the right, in cells, to look like keyboard
+var rowIndents = []int{0, 2, 3, 5}
+
+var keyStyle = tcell.StyleDefault.
+	Background(tcell.ColorGray).
+	Foreground(tcell.ColorBlack)
+
+var nextKeyStyle = tcell.StyleDefault.
+	Background(tcell.ColorBlue).
+	Foreground(tcell.ColorWhite).
+	Bold(true)
+
+// heatStyle colors key from green for 0 heat to red for 1
+func heatStyle(heat float64) tcell.Style {
+	return tcell.StyleDefault.
+		Background(tcell.NewRGBColor(int32(255*heat), int32(255*(1-heat)), 0)).
+		Foreground(tcell.ColorBlack)
+}
+
+func writeKeyboard(s tcell.Screen, kb Keyboard, x, y int) {
+	next, hasNext := kb.Layout.Key(kb.Next)
+	hasNext = hasNext && kb.Next != 0
+	writeKey := func(label string, p layout.Position, kx, width int) {
+		style := keyStyle
+		if heat, ok := kb.Heat[p]; ok {
+			style = heatStyle(heat)
+		}
+		left, right := ' ', ' '
+		if hasNext && next.Position == p {
+			style = nextKeyStyle
+			left, right = '[', ']'
+		}
+		s.SetContent(kx, y+p.Row, left, nil, style)
+		pad := (width - 2 - len([]rune(label))) / 2
+		for i := 1; i < width-1; i++ {
+			s.SetContent(kx+i, y+p.Row, ' ', nil, style)
+		}
+		write(s, label, kx+1+pad, y+p.Row, style)
+		s.SetContent(kx+width-1, y+p.Row, right, nil, style)
+	}
+	for row, keys := range kb.Layout.Rows {
+		for col, r := range []rune(keys) {
+			writeKey(string(r), layout.Position{Row: row, Column: col}, x+rowIndents[row]+col*keyWidth, keyWidth-1)
+		}
+	}
+	// space bar is under keys from c to m in qwerty
+	spaceX := x + rowIndents[layout.BottomRow] + 2*keyWidth
+	writeKey("space", layout.Position{Row: layout.SpaceRow}, spaceX, 6*keyWidth-1)
+}
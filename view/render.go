@@ -2,6 +2,7 @@ package view
 
 import (
 	"fmt"
+	"math"
 	"time"
 	"unicode/utf8"
 
@@ -27,23 +28,72 @@ var blackBar = tcell.StyleDefault.
 var errorStyle = redBar.
 	Foreground(tcell.ColorBlack)
 
+var ghostStyle = tcell.StyleDefault.
+	Background(tcell.ColorBlue).
+	Foreground(tcell.ColorWhite)
+
 type DisplayableData struct {
 	DoneText  []rune
 	WrongText []rune
 	TODOText  []rune
 	Timeline  []float64
 	StartedAt time.Time
+	Now       time.Time     // current time by clock of app
+	Duration  time.Duration // of timed session, zero if not limited
 	WPM       float64
 	Life      float64
 	Zen       bool
 	Offset    int
+	Pass      string // name of drill pass, empty when not in drill
+	PassGoal  string // what is required to succeed in pass
+	PassOK    bool   // whether pass goal is met by what was typed so far
+	Finished  bool   // session is over, waiting for key to continue
+	Ghost     int    // position of ghost in text, -1 when there is no ghost
+	Racers    []Racer
+	Countdown float64 // seconds till start of race
+	Paused    bool
+	Keyboard  *Keyboard // nil when keyboard is not shown
+}
+
+// Racer is progress of one of the players in race
+type Racer struct {
+	Name     string
+	Progress float64 // from 0 to 1
+	Place    int     // 0 when not finished
+	Time     float64 // seconds it took to finish
+	Left     bool    // disconnected before finishing
+	You      bool
 }
 
 func Render(s tcell.Screen, dd DisplayableData) {
 	s.Clear()
 	w, h := s.Size()
 
-	write3colors(s, dd.DoneText, dd.WrongText, dd.TODOText, 2, 3, w-5, h-4)
+	textHeight := h - 4
+	bottom := h - 1 // lines below are taken
+	if len(dd.Racers) > 0 {
+		textHeight -= len(dd.Racers) + 1
+		bottom -= len(dd.Racers)
+		writeRacers(s, dd.Racers, 2, bottom, w-5)
+	}
+	if dd.Keyboard != nil && !dd.Paused {
+		textHeight -= keyboardHeight + 1
+		bottom -= keyboardHeight
+		writeKeyboard(s, *dd.Keyboard, 2, bottom)
+	}
+	if dd.Paused {
+		write(s, "Paused, press any key to continue", 2, 3, tcell.StyleDefault)
+	} else {
+		write3colors(s, dd.DoneText, dd.WrongText, dd.TODOText, dd.Ghost, 2, 3, w-5, textHeight)
+	}
+
+	// shown even in zen mode, as drill and race are not possible without it
+	if dd.Pass != "" {
+		writePass(s, dd, 2, 1)
+	}
+	if dd.Countdown > 0 {
+		write(s, fmt.Sprintf(" Get ready! Race starts in %.0f ", math.Ceil(dd.Countdown)), 2, 1, errorStyle)
+	}
 
 	if !dd.Zen {
 		if dd.Life > 0.0 {
@@ -54,13 +104,18 @@ func Render(s tcell.Screen, dd DisplayableData) {
 				s.SetContent(i*3+1, 0, '♥', nil, lifeStyle)
 			}
 		}
-		write(s, "Type this:", 2, 1, tcell.StyleDefault)
+		if dd.Pass == "" && dd.Countdown <= 0 {
+			write(s, "Type this:", 2, 1, tcell.StyleDefault)
+		}
 
 		// Stats:
 		timer := "Go!"
 		if !dd.StartedAt.IsZero() {
-			seconds := time.Since(dd.StartedAt).Seconds()
+			seconds := dd.Now.Sub(dd.StartedAt).Seconds()
 			timer = fmt.Sprintf("%.1f sec", seconds)
+			if dd.Duration > 0 { // show countdown
+				timer = fmt.Sprintf("%.1f sec left", math.Max(0, dd.Duration.Seconds()-seconds))
+			}
 		}
 		// Show timer
 		x := (w - utf8.RuneCountInString(timer)) / 2
@@ -85,6 +140,12 @@ func Render(s tcell.Screen, dd DisplayableData) {
 		// Show progress
 		done := float64(len(dd.DoneText)) + float64(dd.Offset)
 		progress := done / (done + float64(len(dd.TODOText)+len(dd.WrongText)))
+		if dd.Duration > 0 { // in timed session progress is measured in time
+			progress = 0
+			if !dd.StartedAt.IsZero() {
+				progress = math.Min(1, dd.Now.Sub(dd.StartedAt).Seconds()/dd.Duration.Seconds())
+			}
+		}
 		vBar(s, w-1, 0, int(float64(h)*progress), greenBar)
 		progressIndicator := fmt.Sprintf("%.1f%%", progress*100)
 		x = w - utf8.RuneCountInString(progressIndicator)
@@ -93,6 +154,79 @@ func Render(s tcell.Screen, dd DisplayableData) {
 	s.Show()
 }
 
+// writePass shows which drill pass is typed, what is its goal
+// and whether it is currently reached
+func writePass(s tcell.Screen, dd DisplayableData, x, y int) {
+	header := fmt.Sprintf("%s pass (%s): ", dd.Pass, dd.PassGoal)
+	write(s, header, x, y, tcell.StyleDefault)
+	x += utf8.RuneCountInString(header)
+	result, style := "✘", errorStyle
+	if dd.PassOK {
+		result, style = "✔", greenBar
+	}
+	if dd.Finished {
+		result += " Press any key to continue"
+	}
+	write(s, " "+result+" ", x, y, style)
+}
+
+// writeRacers shows progress bar of each racer, one per line
+func writeRacers(s tcell.Screen, racers []Racer, x, y, w int) {
+	const nameWidth = 12
+	const statusWidth = 12
+	barWidth := w - nameWidth - statusWidth - 2
+	for i, r := range racers {
+		name := []rune(r.Name)
+		if len(name) > nameWidth {
+			name = name[:nameWidth]
+		}
+		style := tcell.StyleDefault
+		barStyle := ghostStyle
+		if r.You {
+			style = style.Bold(true)
+			barStyle = greenBar
+		}
+		write(s, string(name), x, y+i, style)
+
+		bx := x + nameWidth + 1
+		s.SetContent(bx, y+i, '[', nil, tcell.StyleDefault)
+		done := int(float64(barWidth) * math.Min(1, r.Progress))
+		for j := 0; j < barWidth; j++ {
+			st := tcell.StyleDefault
+			if j < done {
+				st = barStyle
+			}
+			s.SetContent(bx+1+j, y+i, ' ', nil, st)
+		}
+		s.SetContent(bx+1+barWidth, y+i, ']', nil, tcell.StyleDefault)
+
+		status := fmt.Sprintf("%.0f%%", r.Progress*100)
+		if r.Place > 0 {
+			status = fmt.Sprintf("%s %.1fs", ordinal(r.Place), r.Time)
+		} else if r.Left {
+			status = "left"
+		}
+		write(s, status, bx+barWidth+3, y+i, style)
+	}
+}
+
+// ordinal formats place like 1st, 2nd, 3rd, 4th...
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
 func vBar(scr tcell.Screen, x, y, h int, style tcell.Style) {
 	for i := 0; i < h; i++ {
 		scr.SetContent(x, y+i, ' ', nil, style)
@@ -106,20 +240,31 @@ func write(scr tcell.Screen, text string, x, y int, style tcell.Style) {
 	}
 }
 
-func write3colors(scr tcell.Screen, done, wrong, todo []rune, x, y, w, h int) {
+// write3colors writes text that is done, typed wrong and that is still to type.
+// ghost is position in done and todo text that is highlighted, -1 for none.
+func write3colors(scr tcell.Screen, done, wrong, todo []rune, ghost, x, y, w, h int) {
 	var cursorX, cursorY int
 	var style tcell.Style
 	var blank bool // turns off printing for computing cursor position
 
-	// put character on screen
-	putC := func(r rune) {
+	// put character on screen, pos is its position in text or -1
+	putC := func(r rune, pos int) {
 		if blank {
 			return // this is just trial run
 		}
-		scr.SetContent(cursorX, cursorY, r, nil, style)
+		st := style
+		if pos >= 0 && pos == ghost {
+			st = ghostStyle
+		}
+		scr.SetContent(cursorX, cursorY, r, nil, st)
 	}
-	putS := func(s []rune) {
-		for _, c := range s {
+	// put string on screen, start is its position in text or -1
+	putS := func(s []rune, start int) {
+		for i, c := range s {
+			pos := -1
+			if start >= 0 {
+				pos = start + i
+			}
 			if !blank && cursorY > y+h {
 				break // Do not type below allowed window
 			}
@@ -127,7 +272,7 @@ func write3colors(scr tcell.Screen, done, wrong, todo []rune, x, y, w, h int) {
 				c = '↡' // If we are on a lower border - show that there will be more text
 			}
 			if c == '\n' {
-				putC('⏎')
+				putC('⏎', pos)
 				// move cursor to new line
 				cursorX = x
 				cursorY++
@@ -137,7 +282,7 @@ func write3colors(scr tcell.Screen, done, wrong, todo []rune, x, y, w, h int) {
 			if c == ' ' {
 				c = '␣'
 			}
-			putC(c)
+			putC(c, pos)
 			cursorX++
 			if cursorX >= x+w { // line wrap
 				cursorX = x
@@ -150,14 +295,15 @@ func write3colors(scr tcell.Screen, done, wrong, todo []rune, x, y, w, h int) {
 	cursorY = y
 	blank = true
 
-	putS(done)
-	putS(wrong)
+	putS(done, -1)
+	putS(wrong, -1)
 
 	// cursor will be in current position if we won't scroll
 
 	// but we will scroll following number of lines
 	scroll := cursorY - y - h/2
 
+	scrolled := len(done) // to know position of text after scrolling
 	// TODO: maybe move this out
 	if scroll > 0 {
 		scrolledLines := 0
@@ -187,18 +333,19 @@ func write3colors(scr tcell.Screen, done, wrong, todo []rune, x, y, w, h int) {
 		}
 	}
 
+	scrolled -= len(done)
 	cursorX = x
 	cursorY = y
 	blank = false
 
 	style = doneStyle
-	putS(done)
+	putS(done, scrolled)
 
 	style = errorStyle
-	putS(wrong)
+	putS(wrong, -1)
 
 	scr.ShowCursor(cursorX, cursorY)
 
 	style = tcell.StyleDefault
-	putS(todo)
+	putS(todo, scrolled+len(done))
 }